@@ -0,0 +1,217 @@
+// Copyright 2019 The protoc-gen-jsonschema Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+
+	"github.com/zchee/protoc-gen-jsonschema/pkg/genjsonschema"
+)
+
+// stringsFlag is a flag which can be given multiple times.
+type stringsFlag []string
+
+// String implements flag.Value.
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+// Set implements flag.Value.
+func (s *stringsFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// generate runs the generate subcommand, which generates the schemas from the FileDescriptorSet files built by
+// "buf build -o" or "protoc --descriptor_set_out" without running protoc.
+//
+// The schemas are the same as the plugin generates with the same parameters, which are given as the flags.
+func generate(args []string, stderr io.Writer) error {
+	var (
+		descriptorSets, files, packages, messages stringsFlag
+
+		fs     = flag.NewFlagSet("generate", flag.ContinueOnError)
+		params flag.FlagSet
+	)
+	fs.SetOutput(stderr)
+	fs.Var(&descriptorSets, "descriptor-set", "FileDescriptorSet file to load, which needs all the imports of the selected files (repeatable)")
+	fs.Var(&files, "file", "path of the proto file to generate the schema of (repeatable)")
+	fs.Var(&packages, "package", "proto package to generate the schemas of its files (repeatable)")
+	fs.Var(&messages, "message", "fully-qualified name of the message to generate the schema of (repeatable)")
+	out := fs.String("out", ".", "output directory")
+	paramFlags(&params)
+	params.VisitAll(func(f *flag.Flag) {
+		fs.Var(f.Value, f.Name, f.Usage)
+	})
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: %s generate --descriptor-set FILE [--file PATH]... [--package NAME]... [--message NAME]... [--out DIR] [parameters]\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+	if len(descriptorSets) == 0 {
+		return errors.New("no --descriptor-set given")
+	}
+	if len(files)+len(packages)+len(messages) == 0 {
+		return errors.New("no --file, --package or --message given")
+	}
+
+	set, err := readDescriptorSets(descriptorSets)
+	if err != nil {
+		return err
+	}
+	registry, err := protodesc.NewFiles(set)
+	if err != nil {
+		return fmt.Errorf("failed to load the descriptor sets: %w", err)
+	}
+
+	var fileToGenerate []string
+	selected := make(map[string]bool)
+	selectFile := func(fd protoreflect.FileDescriptor) {
+		if !selected[fd.Path()] {
+			selected[fd.Path()] = true
+			fileToGenerate = append(fileToGenerate, fd.Path())
+		}
+	}
+	for _, name := range files {
+		fd, err := registry.FindFileByPath(name)
+		if err != nil {
+			return fmt.Errorf("no such file in the descriptor sets: %s", name)
+		}
+		selectFile(fd)
+	}
+	for _, name := range packages {
+		found := false
+		registry.RangeFilesByPackage(protoreflect.FullName(name), func(fd protoreflect.FileDescriptor) bool {
+			found = true
+			selectFile(fd)
+			return true
+		})
+		if !found {
+			return fmt.Errorf("no such package in the descriptor sets: %s", name)
+		}
+	}
+	var mds []protoreflect.MessageDescriptor
+	for _, name := range messages {
+		desc, err := registry.FindDescriptorByName(protoreflect.FullName(name))
+		if err != nil {
+			return fmt.Errorf("no such message in the descriptor sets: %s", name)
+		}
+		md, ok := desc.(protoreflect.MessageDescriptor)
+		if !ok {
+			return fmt.Errorf("not a message in the descriptor sets: %s", name)
+		}
+		mds = append(mds, md)
+	}
+
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: fileToGenerate,
+		Parameter:      proto.String(parameter(fs, &params)),
+		ProtoFile:      set.GetFile(),
+	}
+	resp, err := runRequest(&protogen.Options{ParamFunc: paramFunc(&params)}, req, func(gen *protogen.Plugin) error {
+		return genjsonschema.GenFiles(gen, mds...)
+	})
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return errors.New(resp.GetError())
+	}
+
+	for _, f := range resp.GetFile() {
+		fileName := filepath.Join(*out, filepath.FromSlash(f.GetName()))
+		if err := os.MkdirAll(filepath.Dir(fileName), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(fileName, []byte(f.GetContent()), 0o644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readDescriptorSets reads the FileDescriptorSet files, and returns the set of their files in the topological order
+// as protoc puts them into a CodeGeneratorRequest. The files in more than one of the sets, such as the well-known
+// types, are taken from the first set.
+func readDescriptorSets(names []string) (*descriptorpb.FileDescriptorSet, error) {
+	var fds []*descriptorpb.FileDescriptorProto
+	byName := make(map[string]*descriptorpb.FileDescriptorProto)
+	for _, name := range names {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		set := &descriptorpb.FileDescriptorSet{}
+		if err := proto.Unmarshal(b, set); err != nil {
+			return nil, fmt.Errorf("failed to parse the descriptor set %s: %w", name, err)
+		}
+		for _, fd := range set.GetFile() {
+			if _, ok := byName[fd.GetName()]; !ok {
+				byName[fd.GetName()] = fd
+				fds = append(fds, fd)
+			}
+		}
+	}
+
+	sorted := &descriptorpb.FileDescriptorSet{}
+	seen := make(map[string]bool)
+	var add func(fd *descriptorpb.FileDescriptorProto)
+	add = func(fd *descriptorpb.FileDescriptorProto) {
+		if seen[fd.GetName()] {
+			return
+		}
+		seen[fd.GetName()] = true
+		for _, dep := range fd.GetDependency() {
+			if depFd, ok := byName[dep]; ok {
+				add(depFd)
+			}
+		}
+		sorted.File = append(sorted.File, fd)
+	}
+	for _, fd := range fds {
+		add(fd)
+	}
+
+	return sorted, nil
+}
+
+// parameter returns the plugin parameter of the flags in params which are set in fs, e.g.
+// "allow_null_values=true,draft=2020-12".
+func parameter(fs, params *flag.FlagSet) string {
+	var parts []string
+	fs.Visit(func(f *flag.Flag) {
+		if params.Lookup(f.Name) == nil {
+			return
+		}
+		if getter, ok := f.Value.(flag.Getter); ok {
+			if b, ok := getter.Get().(bool); ok && !b {
+				return
+			}
+		}
+		parts = append(parts, f.Name+"="+f.Value.String())
+	})
+
+	return strings.Join(parts, ",")
+}
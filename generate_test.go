@@ -0,0 +1,190 @@
+// Copyright 2019 The protoc-gen-jsonschema Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// goldenDir is the directory of the fixture .proto files and the golden files of the genjsonschema package.
+const goldenDir = "pkg/genjsonschema/testdata/golden"
+
+// compileProtos compiles the .proto files in the import paths, and returns the FileDescriptorProtos of the files and
+// their dependencies, in the topological order as protoc puts them into a CodeGeneratorRequest.
+func compileProtos(t *testing.T, importPaths []string, names ...string) []*descriptorpb.FileDescriptorProto {
+	t.Helper()
+
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			ImportPaths: importPaths,
+		}),
+		SourceInfoMode: protocompile.SourceInfoStandard,
+	}
+	files, err := compiler.Compile(context.Background(), names...)
+	if err != nil {
+		t.Fatalf("compile %v: %v", names, err)
+	}
+
+	var fds []*descriptorpb.FileDescriptorProto
+	seen := make(map[string]bool)
+	var add func(fd protoreflect.FileDescriptor)
+	add = func(fd protoreflect.FileDescriptor) {
+		if seen[fd.Path()] {
+			return
+		}
+		seen[fd.Path()] = true
+		imports := fd.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			add(imports.Get(i).FileDescriptor)
+		}
+		fds = append(fds, protodesc.ToFileDescriptorProto(fd))
+	}
+	for _, fd := range files {
+		add(fd)
+	}
+
+	return fds
+}
+
+// writeDescriptorSet compiles the .proto files in goldenDir and writes them with their imports as a FileDescriptorSet,
+// as "protoc --include_imports --descriptor_set_out" does.
+func writeDescriptorSet(t *testing.T, names ...string) string {
+	t.Helper()
+
+	// the files are put before their imports, which readDescriptorSets sorts
+	set := &descriptorpb.FileDescriptorSet{File: compileProtos(t, []string{goldenDir}, names...)}
+	slices.Reverse(set.File)
+
+	b, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatalf("proto.Marshal: %v", err)
+	}
+	name := filepath.Join(t.TempDir(), "image.binpb")
+	if err := os.WriteFile(name, b, 0o644); err != nil {
+		t.Fatalf("os.WriteFile: %v", err)
+	}
+
+	return name
+}
+
+func TestGenerate(t *testing.T) {
+	image := writeDescriptorSet(t, "oneofs.proto", "messages.proto")
+
+	tests := map[string]struct {
+		args      []string
+		wantFiles map[string]string // generated file names to the golden files they equal, or empty
+	}{
+		"file": {
+			args: []string{"--file", "oneofs.proto"},
+			wantFiles: map[string]string{
+				"oneofs.jsonschema": "default/oneofs.jsonschema",
+			},
+		},
+		"package": {
+			args: []string{"--package", "golden.messages", "--allow_null_values"},
+			wantFiles: map[string]string{
+				"messages.jsonschema": "allow_null_values/messages.jsonschema",
+			},
+		},
+		"message": {
+			args: []string{"--message", "golden.scalars.Scalars", "--message", "golden.messages.Node", "--encoding", "yaml"},
+			wantFiles: map[string]string{
+				"golden.scalars.Scalars.jsonschema.yaml": "",
+				"golden.messages.Node.jsonschema.yaml":   "",
+			},
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			out := t.TempDir()
+			var stderr bytes.Buffer
+			args := append([]string{"--descriptor-set", image, "--out", out}, tt.args...)
+			if err := generate(args, &stderr); err != nil {
+				t.Fatalf("generate: %v\n%s", err, stderr.String())
+			}
+
+			entries, err := os.ReadDir(out)
+			if err != nil {
+				t.Fatalf("os.ReadDir: %v", err)
+			}
+			if len(entries) != len(tt.wantFiles) {
+				t.Errorf("generated %d files, want %d: %v", len(entries), len(tt.wantFiles), entries)
+			}
+			for fileName, golden := range tt.wantFiles {
+				got, err := os.ReadFile(filepath.Join(out, fileName))
+				if err != nil {
+					t.Errorf("os.ReadFile: %v", err)
+					continue
+				}
+				if golden == "" {
+					continue
+				}
+				want, err := os.ReadFile(filepath.Join(goldenDir, golden))
+				if err != nil {
+					t.Fatalf("os.ReadFile: %v", err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("%s differs from %s:\n%s", fileName, golden, got)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	image := writeDescriptorSet(t, "messages.proto")
+
+	tests := map[string]struct {
+		args    []string
+		wantErr string
+	}{
+		"no descriptor set": {
+			args:    []string{"--file", "messages.proto"},
+			wantErr: "no --descriptor-set given",
+		},
+		"no selection": {
+			args:    []string{"--descriptor-set", image},
+			wantErr: "no --file, --package or --message given",
+		},
+		"unknown file": {
+			args:    []string{"--descriptor-set", image, "--file", "nothing.proto"},
+			wantErr: "no such file in the descriptor sets: nothing.proto",
+		},
+		"unknown package": {
+			args:    []string{"--descriptor-set", image, "--package", "golden.nothing"},
+			wantErr: "no such package in the descriptor sets: golden.nothing",
+		},
+		"unknown message": {
+			args:    []string{"--descriptor-set", image, "--message", "golden.messages.Nothing"},
+			wantErr: "no such message in the descriptor sets: golden.messages.Nothing",
+		},
+		"not a message": {
+			args:    []string{"--descriptor-set", image, "--message", "golden.messages.Node.name"},
+			wantErr: "not a message in the descriptor sets: golden.messages.Node.name",
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			err := generate(append(tt.args, "--out", t.TempDir()), &bytes.Buffer{})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("generate = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,18 +1,25 @@
 module github.com/zchee/protoc-gen-jsonschema
 
-go 1.12
+go 1.23.0
 
 require (
-	github.com/alecthomas/jsonschema v0.0.0-20190122210438-a6952de1bbe6
-	github.com/davecgh/go-spew v1.1.1 // indirect
-	github.com/golang/protobuf/v2 v2.0.0-20190221223758-71c6603a26f9
-	github.com/pkg/errors v0.8.1 // indirect
-	github.com/stretchr/testify v1.3.0 // indirect
+	buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go v1.36.10-20250912141014-52f32327d4b0.1
+	github.com/bufbuild/protocompile v0.14.1
+	github.com/envoyproxy/protoc-gen-validate v1.3.0
+	github.com/xeipuuv/gojsonschema v1.1.0
+	go.uber.org/zap v1.9.1
+	google.golang.org/genproto/googleapis/api v0.0.0-20250707201910-8d1bb00bc6a7
+	google.golang.org/protobuf v1.36.10
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/kr/pretty v0.1.0 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
 	github.com/xeipuuv/gojsonpointer v0.0.0-20180127040702-4e3ac2762d5f // indirect
 	github.com/xeipuuv/gojsonreference v0.0.0-20180127040603-bd5ef7bd5415 // indirect
-	github.com/xeipuuv/gojsonschema v1.1.0
 	go.uber.org/atomic v1.3.2 // indirect
 	go.uber.org/multierr v1.1.0 // indirect
-	go.uber.org/zap v1.9.1
-	google.golang.org/protobuf v0.0.0-20190703204651-15076350e84f
+	golang.org/x/sync v0.11.0 // indirect
+	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127 // indirect
 )
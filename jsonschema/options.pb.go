@@ -0,0 +1,574 @@
+// Copyright 2019 The protoc-gen-jsonschema Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        (unknown)
+// source: jsonschema/options.proto
+
+// Package jsonschema defines the custom options which tune the schemas generated by protoc-gen-jsonschema.
+//
+// The options are applied after the schemas are converted from the types and the validation rules of the elements,
+// so they override them.
+
+package jsonschema
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// FileOptions tunes the schemas of the messages and enums of a file.
+type FileOptions struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// title is the title of the JSON Schema document of the file.
+	Title string `protobuf:"bytes,1,opt,name=title,proto3" json:"title,omitempty"`
+	// hide leaves the messages and enums of the file out of the output. The ones referred by the other files are still
+	// defined in their documents.
+	Hide bool `protobuf:"varint,2,opt,name=hide,proto3" json:"hide,omitempty"`
+	// additional_properties allows or forbids the properties other than the fields in the messages of the file,
+	// overriding the disallow_additional_properties parameter.
+	AdditionalProperties *bool `protobuf:"varint,3,opt,name=additional_properties,json=additionalProperties,proto3,oneof" json:"additional_properties,omitempty"`
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
+}
+
+func (x *FileOptions) Reset() {
+	*x = FileOptions{}
+	mi := &file_jsonschema_options_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileOptions) ProtoMessage() {}
+
+func (x *FileOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_jsonschema_options_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileOptions.ProtoReflect.Descriptor instead.
+func (*FileOptions) Descriptor() ([]byte, []int) {
+	return file_jsonschema_options_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *FileOptions) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *FileOptions) GetHide() bool {
+	if x != nil {
+		return x.Hide
+	}
+	return false
+}
+
+func (x *FileOptions) GetAdditionalProperties() bool {
+	if x != nil && x.AdditionalProperties != nil {
+		return *x.AdditionalProperties
+	}
+	return false
+}
+
+// MessageOptions tunes the schema of a message.
+type MessageOptions struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// title is the title of the schema, overriding the one of the comment_title parameter.
+	Title string `protobuf:"bytes,1,opt,name=title,proto3" json:"title,omitempty"`
+	// examples is the JSON texts of the examples of the message, e.g. `{"name": "foo"}`.
+	Examples []string `protobuf:"bytes,2,rep,name=examples,proto3" json:"examples,omitempty"`
+	// hide leaves the message out of the output of its file. The message is still defined if the other messages refer
+	// to it.
+	Hide bool `protobuf:"varint,3,opt,name=hide,proto3" json:"hide,omitempty"`
+	// additional_properties allows or forbids the properties other than the fields, overriding the
+	// disallow_additional_properties parameter and the file option.
+	AdditionalProperties *bool `protobuf:"varint,4,opt,name=additional_properties,json=additionalProperties,proto3,oneof" json:"additional_properties,omitempty"`
+	// schema is the JSON text of the schema which replaces the whole schema of the message.
+	Schema        string `protobuf:"bytes,5,opt,name=schema,proto3" json:"schema,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MessageOptions) Reset() {
+	*x = MessageOptions{}
+	mi := &file_jsonschema_options_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MessageOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MessageOptions) ProtoMessage() {}
+
+func (x *MessageOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_jsonschema_options_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MessageOptions.ProtoReflect.Descriptor instead.
+func (*MessageOptions) Descriptor() ([]byte, []int) {
+	return file_jsonschema_options_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *MessageOptions) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *MessageOptions) GetExamples() []string {
+	if x != nil {
+		return x.Examples
+	}
+	return nil
+}
+
+func (x *MessageOptions) GetHide() bool {
+	if x != nil {
+		return x.Hide
+	}
+	return false
+}
+
+func (x *MessageOptions) GetAdditionalProperties() bool {
+	if x != nil && x.AdditionalProperties != nil {
+		return *x.AdditionalProperties
+	}
+	return false
+}
+
+func (x *MessageOptions) GetSchema() string {
+	if x != nil {
+		return x.Schema
+	}
+	return ""
+}
+
+// FieldOptions tunes the schema of a field.
+type FieldOptions struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// title is the title of the schema, overriding the one of the comment_title parameter.
+	Title string `protobuf:"bytes,1,opt,name=title,proto3" json:"title,omitempty"`
+	// examples is the JSON texts of the examples of the field, e.g. `"foo"` for a string field.
+	Examples []string `protobuf:"bytes,2,rep,name=examples,proto3" json:"examples,omitempty"`
+	// format is the "format" of the schema, e.g. "date" for a string field.
+	Format string `protobuf:"bytes,3,opt,name=format,proto3" json:"format,omitempty"`
+	// default is the JSON text of the "default" of the schema.
+	Default string `protobuf:"bytes,4,opt,name=default,proto3" json:"default,omitempty"`
+	// const is the JSON text of the only value the field accepts.
+	Const string `protobuf:"bytes,5,opt,name=const,proto3" json:"const,omitempty"`
+	// required requires the property of the field in the message.
+	Required bool `protobuf:"varint,6,opt,name=required,proto3" json:"required,omitempty"`
+	// hide leaves the field out of the properties of the message.
+	Hide bool `protobuf:"varint,7,opt,name=hide,proto3" json:"hide,omitempty"`
+	// schema is the JSON text of the schema which replaces the whole schema of the field.
+	Schema        string `protobuf:"bytes,8,opt,name=schema,proto3" json:"schema,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FieldOptions) Reset() {
+	*x = FieldOptions{}
+	mi := &file_jsonschema_options_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FieldOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FieldOptions) ProtoMessage() {}
+
+func (x *FieldOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_jsonschema_options_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FieldOptions.ProtoReflect.Descriptor instead.
+func (*FieldOptions) Descriptor() ([]byte, []int) {
+	return file_jsonschema_options_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *FieldOptions) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *FieldOptions) GetExamples() []string {
+	if x != nil {
+		return x.Examples
+	}
+	return nil
+}
+
+func (x *FieldOptions) GetFormat() string {
+	if x != nil {
+		return x.Format
+	}
+	return ""
+}
+
+func (x *FieldOptions) GetDefault() string {
+	if x != nil {
+		return x.Default
+	}
+	return ""
+}
+
+func (x *FieldOptions) GetConst() string {
+	if x != nil {
+		return x.Const
+	}
+	return ""
+}
+
+func (x *FieldOptions) GetRequired() bool {
+	if x != nil {
+		return x.Required
+	}
+	return false
+}
+
+func (x *FieldOptions) GetHide() bool {
+	if x != nil {
+		return x.Hide
+	}
+	return false
+}
+
+func (x *FieldOptions) GetSchema() string {
+	if x != nil {
+		return x.Schema
+	}
+	return ""
+}
+
+// EnumOptions tunes the schema of an enum.
+type EnumOptions struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// title is the title of the schema, overriding the one of the comment_title parameter.
+	Title string `protobuf:"bytes,1,opt,name=title,proto3" json:"title,omitempty"`
+	// hide leaves the enum out of the output of its file. The enum is still defined if the messages refer to it.
+	Hide          bool `protobuf:"varint,2,opt,name=hide,proto3" json:"hide,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EnumOptions) Reset() {
+	*x = EnumOptions{}
+	mi := &file_jsonschema_options_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EnumOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EnumOptions) ProtoMessage() {}
+
+func (x *EnumOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_jsonschema_options_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EnumOptions.ProtoReflect.Descriptor instead.
+func (*EnumOptions) Descriptor() ([]byte, []int) {
+	return file_jsonschema_options_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *EnumOptions) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *EnumOptions) GetHide() bool {
+	if x != nil {
+		return x.Hide
+	}
+	return false
+}
+
+// EnumValueOptions tunes the values of the schema of an enum.
+type EnumValueOptions struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// hide leaves the name and the number of the value out of the values of the enum.
+	Hide          bool `protobuf:"varint,1,opt,name=hide,proto3" json:"hide,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EnumValueOptions) Reset() {
+	*x = EnumValueOptions{}
+	mi := &file_jsonschema_options_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EnumValueOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EnumValueOptions) ProtoMessage() {}
+
+func (x *EnumValueOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_jsonschema_options_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EnumValueOptions.ProtoReflect.Descriptor instead.
+func (*EnumValueOptions) Descriptor() ([]byte, []int) {
+	return file_jsonschema_options_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *EnumValueOptions) GetHide() bool {
+	if x != nil {
+		return x.Hide
+	}
+	return false
+}
+
+var file_jsonschema_options_proto_extTypes = []protoimpl.ExtensionInfo{
+	{
+		ExtendedType:  (*descriptorpb.FileOptions)(nil),
+		ExtensionType: (*FileOptions)(nil),
+		Field:         1174,
+		Name:          "jsonschema.file",
+		Tag:           "bytes,1174,opt,name=file",
+		Filename:      "jsonschema/options.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.MessageOptions)(nil),
+		ExtensionType: (*MessageOptions)(nil),
+		Field:         1174,
+		Name:          "jsonschema.message",
+		Tag:           "bytes,1174,opt,name=message",
+		Filename:      "jsonschema/options.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.FieldOptions)(nil),
+		ExtensionType: (*FieldOptions)(nil),
+		Field:         1174,
+		Name:          "jsonschema.field",
+		Tag:           "bytes,1174,opt,name=field",
+		Filename:      "jsonschema/options.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.EnumOptions)(nil),
+		ExtensionType: (*EnumOptions)(nil),
+		Field:         1174,
+		Name:          "jsonschema.enum",
+		Tag:           "bytes,1174,opt,name=enum",
+		Filename:      "jsonschema/options.proto",
+	},
+	{
+		ExtendedType:  (*descriptorpb.EnumValueOptions)(nil),
+		ExtensionType: (*EnumValueOptions)(nil),
+		Field:         1174,
+		Name:          "jsonschema.enum_value",
+		Tag:           "bytes,1174,opt,name=enum_value",
+		Filename:      "jsonschema/options.proto",
+	},
+}
+
+// Extension fields to descriptorpb.FileOptions.
+var (
+	// optional jsonschema.FileOptions file = 1174;
+	E_File = &file_jsonschema_options_proto_extTypes[0]
+)
+
+// Extension fields to descriptorpb.MessageOptions.
+var (
+	// optional jsonschema.MessageOptions message = 1174;
+	E_Message = &file_jsonschema_options_proto_extTypes[1]
+)
+
+// Extension fields to descriptorpb.FieldOptions.
+var (
+	// optional jsonschema.FieldOptions field = 1174;
+	E_Field = &file_jsonschema_options_proto_extTypes[2]
+)
+
+// Extension fields to descriptorpb.EnumOptions.
+var (
+	// optional jsonschema.EnumOptions enum = 1174;
+	E_Enum = &file_jsonschema_options_proto_extTypes[3]
+)
+
+// Extension fields to descriptorpb.EnumValueOptions.
+var (
+	// optional jsonschema.EnumValueOptions enum_value = 1174;
+	E_EnumValue = &file_jsonschema_options_proto_extTypes[4]
+)
+
+var File_jsonschema_options_proto protoreflect.FileDescriptor
+
+const file_jsonschema_options_proto_rawDesc = "" +
+	"\n" +
+	"\x18jsonschema/options.proto\x12\n" +
+	"jsonschema\x1a google/protobuf/descriptor.proto\"\x8b\x01\n" +
+	"\vFileOptions\x12\x14\n" +
+	"\x05title\x18\x01 \x01(\tR\x05title\x12\x12\n" +
+	"\x04hide\x18\x02 \x01(\bR\x04hide\x128\n" +
+	"\x15additional_properties\x18\x03 \x01(\bH\x00R\x14additionalProperties\x88\x01\x01B\x18\n" +
+	"\x16_additional_properties\"\xc2\x01\n" +
+	"\x0eMessageOptions\x12\x14\n" +
+	"\x05title\x18\x01 \x01(\tR\x05title\x12\x1a\n" +
+	"\bexamples\x18\x02 \x03(\tR\bexamples\x12\x12\n" +
+	"\x04hide\x18\x03 \x01(\bR\x04hide\x128\n" +
+	"\x15additional_properties\x18\x04 \x01(\bH\x00R\x14additionalProperties\x88\x01\x01\x12\x16\n" +
+	"\x06schema\x18\x05 \x01(\tR\x06schemaB\x18\n" +
+	"\x16_additional_properties\"\xd0\x01\n" +
+	"\fFieldOptions\x12\x14\n" +
+	"\x05title\x18\x01 \x01(\tR\x05title\x12\x1a\n" +
+	"\bexamples\x18\x02 \x03(\tR\bexamples\x12\x16\n" +
+	"\x06format\x18\x03 \x01(\tR\x06format\x12\x18\n" +
+	"\adefault\x18\x04 \x01(\tR\adefault\x12\x14\n" +
+	"\x05const\x18\x05 \x01(\tR\x05const\x12\x1a\n" +
+	"\brequired\x18\x06 \x01(\bR\brequired\x12\x12\n" +
+	"\x04hide\x18\a \x01(\bR\x04hide\x12\x16\n" +
+	"\x06schema\x18\b \x01(\tR\x06schema\"7\n" +
+	"\vEnumOptions\x12\x14\n" +
+	"\x05title\x18\x01 \x01(\tR\x05title\x12\x12\n" +
+	"\x04hide\x18\x02 \x01(\bR\x04hide\"&\n" +
+	"\x10EnumValueOptions\x12\x12\n" +
+	"\x04hide\x18\x01 \x01(\bR\x04hide:J\n" +
+	"\x04file\x12\x1c.google.protobuf.FileOptions\x18\x96\t \x01(\v2\x17.jsonschema.FileOptionsR\x04file:V\n" +
+	"\amessage\x12\x1f.google.protobuf.MessageOptions\x18\x96\t \x01(\v2\x1a.jsonschema.MessageOptionsR\amessage:N\n" +
+	"\x05field\x12\x1d.google.protobuf.FieldOptions\x18\x96\t \x01(\v2\x18.jsonschema.FieldOptionsR\x05field:J\n" +
+	"\x04enum\x12\x1c.google.protobuf.EnumOptions\x18\x96\t \x01(\v2\x17.jsonschema.EnumOptionsR\x04enum:_\n" +
+	"\n" +
+	"enum_value\x12!.google.protobuf.EnumValueOptions\x18\x96\t \x01(\v2\x1c.jsonschema.EnumValueOptionsR\tenumValueB>Z<github.com/zchee/protoc-gen-jsonschema/jsonschema;jsonschemab\x06proto3"
+
+var (
+	file_jsonschema_options_proto_rawDescOnce sync.Once
+	file_jsonschema_options_proto_rawDescData []byte
+)
+
+func file_jsonschema_options_proto_rawDescGZIP() []byte {
+	file_jsonschema_options_proto_rawDescOnce.Do(func() {
+		file_jsonschema_options_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_jsonschema_options_proto_rawDesc), len(file_jsonschema_options_proto_rawDesc)))
+	})
+	return file_jsonschema_options_proto_rawDescData
+}
+
+var file_jsonschema_options_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_jsonschema_options_proto_goTypes = []any{
+	(*FileOptions)(nil),                   // 0: jsonschema.FileOptions
+	(*MessageOptions)(nil),                // 1: jsonschema.MessageOptions
+	(*FieldOptions)(nil),                  // 2: jsonschema.FieldOptions
+	(*EnumOptions)(nil),                   // 3: jsonschema.EnumOptions
+	(*EnumValueOptions)(nil),              // 4: jsonschema.EnumValueOptions
+	(*descriptorpb.FileOptions)(nil),      // 5: google.protobuf.FileOptions
+	(*descriptorpb.MessageOptions)(nil),   // 6: google.protobuf.MessageOptions
+	(*descriptorpb.FieldOptions)(nil),     // 7: google.protobuf.FieldOptions
+	(*descriptorpb.EnumOptions)(nil),      // 8: google.protobuf.EnumOptions
+	(*descriptorpb.EnumValueOptions)(nil), // 9: google.protobuf.EnumValueOptions
+}
+var file_jsonschema_options_proto_depIdxs = []int32{
+	5,  // 0: jsonschema.file:extendee -> google.protobuf.FileOptions
+	6,  // 1: jsonschema.message:extendee -> google.protobuf.MessageOptions
+	7,  // 2: jsonschema.field:extendee -> google.protobuf.FieldOptions
+	8,  // 3: jsonschema.enum:extendee -> google.protobuf.EnumOptions
+	9,  // 4: jsonschema.enum_value:extendee -> google.protobuf.EnumValueOptions
+	0,  // 5: jsonschema.file:type_name -> jsonschema.FileOptions
+	1,  // 6: jsonschema.message:type_name -> jsonschema.MessageOptions
+	2,  // 7: jsonschema.field:type_name -> jsonschema.FieldOptions
+	3,  // 8: jsonschema.enum:type_name -> jsonschema.EnumOptions
+	4,  // 9: jsonschema.enum_value:type_name -> jsonschema.EnumValueOptions
+	10, // [10:10] is the sub-list for method output_type
+	10, // [10:10] is the sub-list for method input_type
+	5,  // [5:10] is the sub-list for extension type_name
+	0,  // [0:5] is the sub-list for extension extendee
+	0,  // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_jsonschema_options_proto_init() }
+func file_jsonschema_options_proto_init() {
+	if File_jsonschema_options_proto != nil {
+		return
+	}
+	file_jsonschema_options_proto_msgTypes[0].OneofWrappers = []any{}
+	file_jsonschema_options_proto_msgTypes[1].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_jsonschema_options_proto_rawDesc), len(file_jsonschema_options_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 5,
+			NumServices:   0,
+		},
+		GoTypes:           file_jsonschema_options_proto_goTypes,
+		DependencyIndexes: file_jsonschema_options_proto_depIdxs,
+		MessageInfos:      file_jsonschema_options_proto_msgTypes,
+		ExtensionInfos:    file_jsonschema_options_proto_extTypes,
+	}.Build()
+	File_jsonschema_options_proto = out.File
+	file_jsonschema_options_proto_goTypes = nil
+	file_jsonschema_options_proto_depIdxs = nil
+}
@@ -8,9 +8,17 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"io"
 	"log"
+	"os"
+	"path"
+	"path/filepath"
 
-	"google.golang.org/protobuf/protogen"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
 
 	"github.com/zchee/protoc-gen-jsonschema/pkg/genjsonschema"
 )
@@ -29,13 +37,18 @@ func main() {
 	var (
 		flags flag.FlagSet
 		opts  = &protogen.Options{
-			ParamFunc: flags.Set,
+			ParamFunc: paramFunc(&flags),
 		}
 	)
-	flags.Bool("allow_null_values", false, "allow null values")
-	flags.Bool("disallow_additional_properties", false, "disallow additional_properties")
-	flags.Bool("disallow_bigints_as_strings", false, "disallow bigints as strings")
-	flags.Bool("debug", false, "debug mode")
+	paramFlags(&flags)
+
+	if len(os.Args) > 1 && os.Args[1] == "generate" {
+		if err := generate(os.Args[2:], os.Stderr); err != nil {
+			fmt.Fprintf(os.Stderr, "%s generate: %v\n", filepath.Base(os.Args[0]), err)
+			os.Exit(1)
+		}
+		return
+	}
 
 	// flag.Parse()
 	// if flagVersion {
@@ -43,17 +56,102 @@ func main() {
 	// 	return
 	// }
 
-	protogen.Run(opts, func(gen *protogen.Plugin) error {
-		for _, f := range gen.Files {
-			if !f.Generate {
-				continue
+	if err := run(opts, genFiles); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", filepath.Base(os.Args[0]), err)
+		os.Exit(1)
+	}
+}
+
+// genFiles generates the schemas of the files to generate.
+func genFiles(gen *protogen.Plugin) error {
+	return genjsonschema.GenFiles(gen)
+}
+
+// paramFlags registers the parameters of the plugin to the flag set.
+func paramFlags(flags *flag.FlagSet) {
+	flags.Bool("allow_null_values", false, "allow null values")
+	flags.Bool("comment_title", false, "promote the first sentence of comments to title")
+	flags.Bool("disallow_additional_properties", false, "disallow additional_properties")
+	flags.Bool("disallow_bigints_as_strings", false, "disallow bigints as strings")
+	flags.Bool("strip_comment_directives", false, "strip lint directives such as buf:lint:ignore from comments")
+	flags.String("oneof_encoding", "strict", "encoding of oneof groups (strict or lenient)")
+	flags.String("property_naming", "proto", "naming of message properties (proto, json or both)")
+	flags.String("draft", "04", "JSON Schema draft of the output (04, 06, 07, 2019-09 or 2020-12)")
+	flags.String("output_format", "jsonschema", "format of the output (jsonschema, openapi3 or openapi3.1)")
+	flags.String("encoding", "json", "encoding of the output files (json or yaml)")
+	flags.Int("indent", 4, "indentation width of the output files (4 for json and 2 for yaml by default, 2 or more for yaml)")
+	flags.String("property_order", "declaration", "order of message properties (declaration or alphabetical)")
+	flags.Int("parallelism", 0, "maximum number of conversions of files and messages running at once (GOMAXPROCS by default)")
+	flags.Bool("debug", false, "debug mode")
+}
+
+// paramFunc returns the protogen.Options.ParamFunc which sets the flags, where a boolean flag without a value, e.g.
+// "allow_null_values" rather than "allow_null_values=true", is set to true as on the command line.
+func paramFunc(flags *flag.FlagSet) func(name, value string) error {
+	return func(name, value string) error {
+		if f := flags.Lookup(name); f != nil && value == "" {
+			if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+				value = "true"
 			}
+		}
+		return flags.Set(name, value)
+	}
+}
+
+// run is the same as protogen.Options.Run, except that it assigns a placeholder go_package to
+// the files which do not have it, because the JSON Schema does not care about Go import paths.
+func run(opts *protogen.Options, f func(*protogen.Plugin) error) error {
+	in, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return err
+	}
+	req := &pluginpb.CodeGeneratorRequest{}
+	if err := proto.Unmarshal(in, req); err != nil {
+		return err
+	}
+
+	resp, err := runRequest(opts, req, f)
+	if err != nil {
+		return err
+	}
 
-			filename := f.GeneratedFilenamePrefix + ".jsonschema"
-			f.GoImportPath = protogen.GoImportPath(f.Proto.GetName())
-			g := gen.NewGeneratedFile(filename, f.GoImportPath)
-			genjsonschema.Gen(gen, f, g)
+	out, err := proto.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stdout.Write(out); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// runRequest runs f for the request and returns the response, as protogen.Options.Run does for the request read
+// from stdin.
+func runRequest(opts *protogen.Options, req *pluginpb.CodeGeneratorRequest, f func(*protogen.Plugin) error) (*pluginpb.CodeGeneratorResponse, error) {
+	for _, fd := range req.GetProtoFile() {
+		if fd.GetOptions().GetGoPackage() != "" {
+			continue
 		}
-		return nil
-	})
+		if fd.Options == nil {
+			fd.Options = &descriptorpb.FileOptions{}
+		}
+		fd.Options.GoPackage = proto.String(path.Join("jsonschema.invalid", path.Dir(fd.GetName())))
+	}
+
+	gen, err := opts.New(req)
+	if err != nil {
+		return nil, err
+	}
+	gen.SupportedFeatures = uint64(pluginpb.CodeGeneratorResponse_FEATURE_PROTO3_OPTIONAL | pluginpb.CodeGeneratorResponse_FEATURE_SUPPORTS_EDITIONS)
+	// the plugin accepts the editions from proto2 to 2024, the ones whose features protobuf-go resolves
+	gen.SupportedEditionsMinimum = descriptorpb.Edition_EDITION_PROTO2
+	gen.SupportedEditionsMaximum = descriptorpb.Edition_EDITION_2024
+	if err := f(gen); err != nil {
+		// Errors from the plugin function are reported by setting the
+		// error field in the CodeGeneratorResponse.
+		gen.Error(err)
+	}
+
+	return gen.Response(), nil
 }
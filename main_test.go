@@ -0,0 +1,160 @@
+// Copyright 2019 The protoc-gen-jsonschema Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestRunRequestErrors(t *testing.T) {
+	dir := t.TempDir()
+	for name, source := range map[string]string{
+		"profiles.proto": `syntax = "proto3";
+
+package test.errors;
+
+import "jsonschema/options.proto";
+
+message Profile {
+  string name = 1 [(jsonschema.field) = {default: "anonymous"}];
+  int32 age = 2 [(jsonschema.field) = {examples: "twenty"}];
+  string nick = 3;
+}
+`,
+		"accounts.proto": `syntax = "proto3";
+
+package test.errors;
+
+import "jsonschema/options.proto";
+
+message Account {
+  option (jsonschema.message) = {schema: "[]"};
+
+  string id = 1;
+}
+
+message Group {
+  string title = 1 [(jsonschema.field) = {const: "admins"}];
+}
+`,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(source), 0o644); err != nil {
+			t.Fatalf("os.WriteFile: %v", err)
+		}
+	}
+
+	fileToGenerate := []string{"profiles.proto", "accounts.proto"}
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: fileToGenerate,
+		ProtoFile:      compileProtos(t, []string{dir, "."}, fileToGenerate...),
+	}
+
+	var flags flag.FlagSet
+	paramFlags(&flags)
+	resp, err := runRequest(&protogen.Options{ParamFunc: paramFunc(&flags)}, req, genFiles)
+	if err != nil {
+		t.Fatalf("runRequest: %v", err)
+	}
+
+	if len(resp.GetFile()) > 0 {
+		t.Errorf("generated %d files for the failing conversion", len(resp.GetFile()))
+	}
+	for _, want := range []string{
+		`profiles.proto:8:3: test.errors.Profile.name: invalid JSON of default "anonymous"`,
+		`profiles.proto:9:3: test.errors.Profile.age: invalid JSON of examples "twenty"`,
+		`accounts.proto:7:1: test.errors.Account: invalid schema "[]": not an object or a boolean`,
+		`accounts.proto:14:3: test.errors.Group.title: invalid JSON of const "admins"`,
+	} {
+		if !strings.Contains(resp.GetError(), want) {
+			t.Errorf("CodeGeneratorResponse.error does not report %q:\n%s", want, resp.GetError())
+		}
+	}
+	if strings.Contains(resp.GetError(), "test.errors.Profile.nick") {
+		t.Errorf("CodeGeneratorResponse.error reports the valid field test.errors.Profile.nick:\n%s", resp.GetError())
+	}
+}
+
+func TestRunRequestParameters(t *testing.T) {
+	dir := t.TempDir()
+	source := `syntax = "proto3";
+
+package test.parameters;
+
+// Pods.
+
+// Pod is a collection of containers.
+// buf:lint:ignore FIELD_LOWER_SNAKE_CASE
+message Pod {
+  string name = 1;
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "pods.proto"), []byte(source), 0o644); err != nil {
+		t.Fatalf("os.WriteFile: %v", err)
+	}
+	protoFile := compileProtos(t, []string{dir}, "pods.proto")
+
+	tests := []struct {
+		parameter string
+		want      []string
+		wantNot   []string
+	}{
+		{
+			// protoc passes the boolean parameters without a value as they are
+			parameter: "comment_title,strip_comment_directives",
+			want:      []string{`"title": "Pods"`},
+			wantNot:   []string{"buf:lint:ignore"},
+		},
+		{
+			parameter: "comment_title=true,strip_comment_directives=false",
+			want:      []string{`"title": "Pods"`, "buf:lint:ignore"},
+		},
+		{
+			parameter: "comment_title=false,strip_comment_directives=true",
+			want:      []string{`"description": "Pods.\n\nPod is a collection of containers."`},
+			wantNot:   []string{`"title"`, "buf:lint:ignore"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.parameter, func(t *testing.T) {
+			req := &pluginpb.CodeGeneratorRequest{
+				FileToGenerate: []string{"pods.proto"},
+				Parameter:      &tt.parameter,
+				ProtoFile:      protoFile,
+			}
+			var flags flag.FlagSet
+			paramFlags(&flags)
+			resp, err := runRequest(&protogen.Options{ParamFunc: paramFunc(&flags)}, req, genFiles)
+			if err != nil {
+				t.Fatalf("runRequest: %v", err)
+			}
+			if resp.Error != nil {
+				t.Fatalf("CodeGeneratorResponse.error: %s", resp.GetError())
+			}
+			if len(resp.GetFile()) != 1 {
+				t.Fatalf("generated %d files, want 1", len(resp.GetFile()))
+			}
+
+			content := resp.GetFile()[0].GetContent()
+			for _, want := range tt.want {
+				if !strings.Contains(content, want) {
+					t.Errorf("%s does not contain %s:\n%s", resp.GetFile()[0].GetName(), want, content)
+				}
+			}
+			for _, want := range tt.wantNot {
+				if strings.Contains(content, want) {
+					t.Errorf("%s contains %s:\n%s", resp.GetFile()[0].GetName(), want, content)
+				}
+			}
+		})
+	}
+}
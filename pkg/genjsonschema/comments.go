@@ -0,0 +1,102 @@
+// Copyright 2019 The protoc-gen-jsonschema Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genjsonschema
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// commentDirectivePrefixes is the prefixes of the comment lines which are directives for the tools rather than
+// documentation.
+var commentDirectivePrefixes = []string{
+	"buf:lint:",
+	"@exclude",
+	"protolint:",
+}
+
+// describe sets the description of the schema from the comments of the proto element.
+//
+// The leading detached, leading and trailing comments are joined as paragraphs. If the comment_title parameter is
+// given, the first sentence is promoted to the title.
+func (c *converter) describe(jsonSchemaType *Type, desc protoreflect.Descriptor) {
+	description := c.commentsText(desc)
+	if description == "" {
+		return
+	}
+
+	if c.opts.CommentTitle {
+		jsonSchemaType.Title, description = firstSentence(description)
+	}
+	jsonSchemaType.Description = description
+}
+
+// commentsText returns the text of the comments of the proto element joined as paragraphs.
+//
+// The comments are empty if the file of the element has no SourceCodeInfo.
+func (c *converter) commentsText(desc protoreflect.Descriptor) string {
+	file := desc.ParentFile()
+	if file == nil {
+		return ""
+	}
+	loc := file.SourceLocations().ByDescriptor(desc)
+
+	var paragraphs []string
+	for _, comment := range append(loc.LeadingDetachedComments, loc.LeadingComments, loc.TrailingComments) {
+		if text := c.commentText(comment); text != "" {
+			paragraphs = append(paragraphs, text)
+		}
+	}
+
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// commentText returns the text of the comment, without the directive lines if the strip_comment_directives
+// parameter is given.
+func (c *converter) commentText(comment string) string {
+	var lines []string
+	for _, line := range strings.Split(comment, "\n") {
+		// protoc keeps the space after the comment marker
+		line = strings.TrimRight(strings.TrimPrefix(line, " "), " \t")
+		if c.opts.StripCommentDirectives && isCommentDirective(line) {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// isCommentDirective reports whether the comment line is a directive for the tools.
+func isCommentDirective(line string) bool {
+	line = strings.TrimSpace(line)
+	for _, prefix := range commentDirectivePrefixes {
+		if strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// firstSentence splits the text into its first sentence, without the trailing period, and the rest of the text.
+//
+// The sentence ends with a period followed by a space or a newline, or with the end of the first paragraph.
+func firstSentence(text string) (sentence, rest string) {
+	end, next := len(text), len(text)
+	for i := 0; i < len(text); i++ {
+		if text[i] == '.' && (i+1 == len(text) || text[i+1] == ' ' || text[i+1] == '\n') {
+			end, next = i, i+1
+			break
+		}
+		if strings.HasPrefix(text[i:], "\n\n") {
+			end, next = i, i
+			break
+		}
+	}
+
+	return strings.Join(strings.Fields(text[:end]), " "), strings.TrimSpace(text[next:])
+}
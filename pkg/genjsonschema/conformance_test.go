@@ -0,0 +1,350 @@
+// Copyright 2019 The protoc-gen-jsonschema Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genjsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xeipuuv/gojsonschema"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+var (
+	conformanceSeed      = flag.Int64("conformance.seed", 1, "seed of the random messages of the conformance tests")
+	conformanceInstances = flag.Int("conformance.instances", 20, "number of the random messages of each message type")
+)
+
+// conformanceCases is the pairs of the parameters and the protojson options whose output the schemas must accept.
+//
+// protojson writes null for the unpopulated fields with presence under EmitUnpopulated, which the schemas accept only
+// with allow_null_values. The cases of nullRejected are the known failures of it, whose errors of the null values are
+// logged rather than failing the test.
+var conformanceCases = []struct {
+	parameter    string
+	marshal      protojson.MarshalOptions
+	nullRejected bool
+}{
+	{parameter: "property_naming=proto", marshal: protojson.MarshalOptions{UseProtoNames: true}},
+	{parameter: "property_naming=json", marshal: protojson.MarshalOptions{}},
+	{parameter: "property_naming=both,disallow_additional_properties", marshal: protojson.MarshalOptions{UseProtoNames: true}},
+	{parameter: "property_naming=both,disallow_additional_properties", marshal: protojson.MarshalOptions{}},
+	{parameter: "property_naming=proto", marshal: protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}, nullRejected: true},
+	{parameter: "property_naming=json,disallow_additional_properties", marshal: protojson.MarshalOptions{EmitUnpopulated: true}, nullRejected: true},
+	{parameter: "property_naming=proto,allow_null_values", marshal: protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}},
+	{parameter: "property_naming=json,allow_null_values,disallow_additional_properties", marshal: protojson.MarshalOptions{EmitUnpopulated: true}},
+}
+
+// randomMessages builds the random instances of the message types.
+type randomMessages struct {
+	*rand.Rand
+	files *protoregistry.Files
+}
+
+// maxDepth is the depth of the nested messages beyond which the optional message fields are left unset.
+const maxDepth = 3
+
+// message returns a random instance of the message type.
+func (r *randomMessages) message(md protoreflect.MessageDescriptor, depth int) *dynamicpb.Message {
+	m := dynamicpb.NewMessage(md)
+	if r.wellKnown(m, depth) {
+		return m
+	}
+
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if oneof := fd.ContainingOneof(); oneof != nil && m.WhichOneof(oneof) != nil {
+			continue
+		}
+		if fd.Cardinality() != protoreflect.Required {
+			if r.Intn(2) == 0 || (fd.Message() != nil && depth >= maxDepth) {
+				continue
+			}
+		}
+
+		switch {
+		case fd.IsMap():
+			mp := m.Mutable(fd).Map()
+			for n := r.Intn(3); n > 0; n-- {
+				mp.Set(r.value(fd.MapKey(), depth).MapKey(), r.value(fd.MapValue(), depth))
+			}
+		case fd.IsList():
+			list := m.Mutable(fd).List()
+			for n := r.Intn(3); n > 0; n-- {
+				list.Append(r.value(fd, depth))
+			}
+		default:
+			m.Set(fd, r.value(fd, depth))
+		}
+	}
+
+	return m
+}
+
+// value returns a random value of the singular field, or of an element of the repeated field.
+func (r *randomMessages) value(fd protoreflect.FieldDescriptor, depth int) protoreflect.Value {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return protoreflect.ValueOfBool(r.Intn(2) == 0)
+	case protoreflect.EnumKind:
+		values := fd.Enum().Values()
+		return protoreflect.ValueOfEnum(values.Get(r.Intn(values.Len())).Number())
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return protoreflect.ValueOfInt32(r.pick(int32(r.Uint32()), int32(math.MinInt32), int32(math.MaxInt32), int32(0)).(int32))
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return protoreflect.ValueOfInt64(r.pick(int64(r.Uint64()), int64(math.MinInt64), int64(math.MaxInt64), int64(0)).(int64))
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return protoreflect.ValueOfUint32(r.pick(r.Uint32(), uint32(math.MaxUint32), uint32(0)).(uint32))
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return protoreflect.ValueOfUint64(r.pick(r.Uint64(), uint64(math.MaxUint64), uint64(0)).(uint64))
+	case protoreflect.FloatKind:
+		return protoreflect.ValueOfFloat32(r.pick(float32(r.NormFloat64()*1e6), float32(math.MaxFloat32), float32(0)).(float32))
+	case protoreflect.DoubleKind:
+		return protoreflect.ValueOfFloat64(r.pick(r.NormFloat64()*1e12, math.MaxFloat64, -math.SmallestNonzeroFloat64).(float64))
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(r.string())
+	case protoreflect.BytesKind:
+		b := make([]byte, r.Intn(8))
+		r.Read(b)
+		return protoreflect.ValueOfBytes(b)
+	default: // MessageKind, GroupKind
+		return protoreflect.ValueOfMessage(r.message(fd.Message(), depth+1))
+	}
+}
+
+// pick returns one of the edge values at times, or v otherwise.
+func (r *randomMessages) pick(v interface{}, edges ...interface{}) interface{} {
+	if r.Intn(4) == 0 {
+		return edges[r.Intn(len(edges))]
+	}
+	return v
+}
+
+// string returns a random valid UTF-8 string.
+func (r *randomMessages) string() string {
+	const letters = "abcXYZ019 _-./\"\\\t日本語🙂"
+	runes := []rune(letters)
+	var b strings.Builder
+	for n := r.Intn(8); n > 0; n-- {
+		b.WriteRune(runes[r.Intn(len(runes))])
+	}
+	return b.String()
+}
+
+// wellKnown populates the well-known type with a random value which protojson can marshal, and reports whether m is a
+// well-known type. The other fields of the well-known types are left unset, because their values are not free.
+func (r *randomMessages) wellKnown(m *dynamicpb.Message, depth int) bool {
+	fields := m.Descriptor().Fields()
+	field := func(name protoreflect.Name) protoreflect.FieldDescriptor { return fields.ByName(name) }
+
+	switch m.Descriptor().FullName() {
+	case "google.protobuf.Timestamp":
+		// 0001-01-01T00:00:00Z to 9999-12-31T23:59:59Z
+		m.Set(field("seconds"), protoreflect.ValueOfInt64(-62135596800+r.Int63n(253402300799+62135596800)))
+		m.Set(field("nanos"), protoreflect.ValueOfInt32(r.pick(r.Int31n(1e9), int32(0)).(int32)))
+	case "google.protobuf.Duration":
+		seconds, nanos := r.Int63n(315576000000), r.pick(r.Int31n(1e9), int32(0)).(int32)
+		if r.Intn(2) == 0 {
+			seconds, nanos = -seconds, -nanos
+		}
+		m.Set(field("seconds"), protoreflect.ValueOfInt64(seconds))
+		m.Set(field("nanos"), protoreflect.ValueOfInt32(nanos))
+	case "google.protobuf.FieldMask":
+		paths := m.Mutable(field("paths")).List()
+		for n := r.Intn(3); n > 0; n-- {
+			paths.Append(protoreflect.ValueOfString([]string{"foo", "foo_bar", "foo.bar_baz"}[r.Intn(3)]))
+		}
+	case "google.protobuf.Value":
+		r.jsonValue(m, depth)
+	case "google.protobuf.ListValue":
+		values := m.Mutable(field("values")).List()
+		for n := r.Intn(3); n > 0; n-- {
+			values.Append(protoreflect.ValueOfMessage(r.message(field("values").Message(), depth+1)))
+		}
+	case "google.protobuf.Struct":
+		fieldsMap := m.Mutable(field("fields")).Map()
+		for n := r.Intn(3); n > 0; n-- {
+			key := protoreflect.ValueOfString(r.string()).MapKey()
+			fieldsMap.Set(key, protoreflect.ValueOfMessage(r.message(field("fields").MapValue().Message(), depth+1)))
+		}
+	case "google.protobuf.Any":
+		desc, err := r.files.FindDescriptorByName("google.protobuf.Duration")
+		if err != nil {
+			return true
+		}
+		b, err := proto.Marshal(r.message(desc.(protoreflect.MessageDescriptor), depth+1))
+		if err != nil {
+			panic(err)
+		}
+		m.Set(field("type_url"), protoreflect.ValueOfString("type.googleapis.com/google.protobuf.Duration"))
+		m.Set(field("value"), protoreflect.ValueOfBytes(b))
+	case "google.protobuf.Empty":
+	default:
+		// the wrappers have no constraints on their values
+		return false
+	}
+
+	return true
+}
+
+// jsonValue populates the google.protobuf.Value with a random JSON value.
+func (r *randomMessages) jsonValue(m *dynamicpb.Message, depth int) {
+	fields := m.Descriptor().Fields()
+	kinds := []protoreflect.Name{"null_value", "number_value", "string_value", "bool_value", "struct_value", "list_value"}
+	if depth >= maxDepth {
+		kinds = kinds[:4]
+	}
+
+	switch kind := fields.ByName(kinds[r.Intn(len(kinds))]); kind.Name() {
+	case "null_value":
+		m.Set(kind, protoreflect.ValueOfEnum(0))
+	case "number_value":
+		m.Set(kind, protoreflect.ValueOfFloat64(r.NormFloat64()))
+	default:
+		m.Set(kind, r.value(kind, depth))
+	}
+}
+
+// jsonPointer returns the JSON Pointer of the location of the validation error.
+func jsonPointer(err gojsonschema.ResultError) string {
+	return strings.TrimPrefix(err.Context().String("/"), gojsonschema.STRING_CONTEXT_ROOT)
+}
+
+func TestConformance(t *testing.T) {
+	protos, err := filepath.Glob(filepath.Join(goldenDir, "*.proto"))
+	if err != nil {
+		t.Fatalf("filepath.Glob: %v", err)
+	}
+
+	for _, proto := range protos {
+		name := filepath.Base(proto)
+		t.Run(name, func(t *testing.T) {
+			fds := compileProtos(t, goldenDir, name)
+			files, err := protodesc.NewFiles(&descriptorpb.FileDescriptorSet{File: fds})
+			if err != nil {
+				t.Fatalf("protodesc.NewFiles: %v", err)
+			}
+			fd, err := files.FindFileByPath(name)
+			if err != nil {
+				t.Fatalf("FindFileByPath: %v", err)
+			}
+
+			var messages []protoreflect.MessageDescriptor
+			var walk func(protoreflect.MessageDescriptors)
+			walk = func(mds protoreflect.MessageDescriptors) {
+				for i := 0; i < mds.Len(); i++ {
+					if md := mds.Get(i); !md.IsMapEntry() {
+						messages = append(messages, md)
+						walk(md.Messages())
+					}
+				}
+			}
+			walk(fd.Messages())
+
+			for _, tc := range conformanceCases {
+				tc := tc
+				tc.marshal.Resolver = dynamicpb.NewTypes(files)
+
+				schema := generate(t, tc.parameter, fds...)[strings.TrimSuffix(name, ".proto")+".jsonschema"]
+				root, err := gojsonschema.NewSchema(gojsonschema.NewStringLoader(schema))
+				if err != nil {
+					t.Fatalf("gojsonschema.NewSchema: %v", err)
+				}
+
+				r := &randomMessages{Rand: rand.New(rand.NewSource(*conformanceSeed)), files: files}
+				nullErrors := 0
+				for _, md := range messages {
+					// the documents of the nested messages are checked against their definitions only, as the root
+					// accepts the top-level messages
+					validators := map[string]*gojsonschema.Schema{"$ref": definitionSchema(t, schema, string(md.FullName()))}
+					if md.Parent() == fd {
+						validators["root"] = root
+					}
+
+					for i := 0; i < *conformanceInstances; i++ {
+						document, err := tc.marshal.Marshal(r.message(md, 0))
+						if err != nil {
+							t.Fatalf("protojson.Marshal: %v", err)
+						}
+						for against, validator := range validators {
+							result, err := validator.Validate(gojsonschema.NewBytesLoader(document))
+							if err != nil {
+								t.Fatalf("Validate: %v", err)
+							}
+							if !result.Valid() && tc.nullRejected {
+								// the document is a known failure if it is valid without the null values
+								withoutNull := withoutNullMembers(t, document)
+								if result, err = validator.Validate(gojsonschema.NewBytesLoader(withoutNull)); err != nil {
+									t.Fatalf("Validate: %v", err)
+								}
+								if result.Valid() {
+									nullErrors++
+									continue
+								}
+							}
+							for _, resultErr := range result.Errors() {
+								t.Errorf("%s against %s (%s, UseProtoNames=%t, EmitUnpopulated=%t): %q: %s\n%s", md.FullName(), against, tc.parameter, tc.marshal.UseProtoNames, tc.marshal.EmitUnpopulated, jsonPointer(resultErr), resultErr.Description(), document)
+							}
+						}
+					}
+				}
+				if nullErrors > 0 {
+					t.Logf("known failure (%s, UseProtoNames=%t, EmitUnpopulated=%t): %d documents rejected for the null values of the unpopulated fields", tc.parameter, tc.marshal.UseProtoNames, tc.marshal.EmitUnpopulated, nullErrors)
+				}
+			}
+		})
+	}
+}
+
+// withoutNullMembers returns the JSON document without the members whose values are null.
+func withoutNullMembers(t *testing.T, document []byte) []byte {
+	t.Helper()
+
+	dec := json.NewDecoder(bytes.NewReader(document))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("json.Decode: %v", err)
+	}
+
+	var strip func(v interface{})
+	strip = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for key, value := range v {
+				if value == nil {
+					delete(v, key)
+					continue
+				}
+				strip(value)
+			}
+		case []interface{}:
+			for _, value := range v {
+				strip(value)
+			}
+		}
+	}
+	strip(v)
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("json.Marshal: %v", err)
+	}
+
+	return b
+}
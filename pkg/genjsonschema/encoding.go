@@ -0,0 +1,91 @@
+// Copyright 2019 The protoc-gen-jsonschema Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genjsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// list of the encodings of the output files.
+const (
+	encodingJSON = "json"
+	encodingYAML = "yaml"
+)
+
+// defaultIndents is the default indentation widths of the encodings.
+var defaultIndents = map[string]int{
+	encodingJSON: 4,
+	encodingYAML: 2,
+}
+
+// extension returns the extension of the output files in the encoding, where base is the extension of JSON files.
+func (opts *options) extension(base string) string {
+	if opts.encoding == encodingYAML {
+		return strings.TrimSuffix(base, ".json") + ".yaml"
+	}
+	return base
+}
+
+// marshal encodes v, which is built by the emitter, in the encoding of opts.
+//
+// The members of the objects are written in the order the emitter sets them, so the same input always produces the
+// same bytes.
+func (opts *options) marshal(v interface{}) ([]byte, error) {
+	indent := opts.indent
+	if indent < 0 {
+		indent = defaultIndents[opts.encoding]
+	}
+
+	jsonBytes, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	switch opts.encoding {
+	case encodingYAML:
+		// JSON is YAML, and the parsed node keeps the order of the members
+		var node yaml.Node
+		if err := yaml.Unmarshal(jsonBytes, &node); err != nil {
+			return nil, err
+		}
+		resetStyle(&node)
+
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(indent)
+		if err := enc.Encode(&node); err != nil {
+			return nil, err
+		}
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+
+	default:
+		var buf bytes.Buffer
+		if indent == 0 {
+			buf.Write(jsonBytes)
+		} else if err := json.Indent(&buf, jsonBytes, "", strings.Repeat(" ", indent)); err != nil {
+			return nil, err
+		}
+		buf.WriteByte('\n')
+
+		return buf.Bytes(), nil
+	}
+}
+
+// resetStyle resets the styles of the node and its descendants parsed from JSON, such as the quoted strings and the
+// flow mappings, to let the encoder write them in the block style.
+func resetStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, n := range node.Content {
+		resetStyle(n)
+	}
+}
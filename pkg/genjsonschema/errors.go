@@ -0,0 +1,51 @@
+// Copyright 2019 The protoc-gen-jsonschema Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genjsonschema
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// conversionError is an error which occurred while converting a proto element.
+type conversionError struct {
+	desc protoreflect.Descriptor
+	err  error
+}
+
+// Error implements error.
+//
+// The message is prefixed by the proto file, the source position if the file has SourceCodeInfo, and the
+// fully-qualified name of the element, e.g. "foo/v1/foo.proto:12:3: foo.v1.Foo.bar: no such message type".
+func (e *conversionError) Error() string {
+	if fd, ok := e.desc.(protoreflect.FileDescriptor); ok {
+		return fmt.Sprintf("%s: %v", fd.Path(), e.err)
+	}
+
+	file := e.desc.ParentFile()
+	if file == nil {
+		return fmt.Sprintf("%s: %v", e.desc.FullName(), e.err)
+	}
+
+	pos := file.Path()
+	if loc := file.SourceLocations().ByDescriptor(e.desc); loc.Path != nil {
+		// SourceCodeInfo lines and columns are zero-based
+		pos = fmt.Sprintf("%s:%d:%d", pos, loc.StartLine+1, loc.StartColumn+1)
+	}
+
+	return fmt.Sprintf("%s: %s: %v", pos, e.desc.FullName(), e.err)
+}
+
+// Unwrap returns the underlying error.
+func (e *conversionError) Unwrap() error {
+	return e.err
+}
+
+// addError records the error which occurred while converting desc. The conversion continues, so that all the
+// failing elements are reported at once.
+func (c *converter) addError(desc protoreflect.Descriptor, err error) {
+	c.errs = append(c.errs, &conversionError{desc: desc, err: err})
+}
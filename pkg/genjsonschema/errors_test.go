@@ -0,0 +1,46 @@
+// Copyright 2019 The protoc-gen-jsonschema Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genjsonschema
+
+import (
+	"errors"
+	"testing"
+
+	"google.golang.org/protobuf/reflect/protodesc"
+)
+
+func TestConversionError(t *testing.T) {
+	fd, err := protodesc.NewFile(fileDescriptorProto(t, `
+name: "errors/v1/errors.proto" package: "test.errors" syntax: "proto3"
+message_type {
+  name: "Foo"
+  field { name: "bar" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "bar" }
+  field { name: "baz" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "baz" }
+}
+source_code_info {
+  location { path: [4, 0, 2, 0] span: [11, 2, 19] }
+}`), nil)
+	if err != nil {
+		t.Fatalf("protodesc.NewFile: %v", err)
+	}
+	fields := fd.Messages().Get(0).Fields()
+	errBoom := errors.New("boom")
+
+	c := new(converter)
+	c.addError(fields.Get(0), errBoom)
+	c.addError(fields.Get(1), errors.New("bang"))
+	c.errs = append(c.errs, &conversionError{desc: fd, err: errors.New("file is already registered")})
+
+	err = errors.Join(c.errs...)
+	want := "errors/v1/errors.proto:12:3: test.errors.Foo.bar: boom\n" +
+		"errors/v1/errors.proto: test.errors.Foo.baz: bang\n" +
+		"errors/v1/errors.proto: file is already registered"
+	if got := err.Error(); got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if !errors.Is(err, errBoom) {
+		t.Errorf("errors.Is(%v, %v) = false, want true", err, errBoom)
+	}
+}
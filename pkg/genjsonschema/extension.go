@@ -0,0 +1,41 @@
+// Copyright 2019 The protoc-gen-jsonschema Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genjsonschema
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// getExtension returns the value of the extension in the options of the descriptor, or nil if it is not set.
+//
+// The options of the descriptors which are not built by the generated Go packages, such as the ones loaded from
+// descriptor sets, may hold the extension as unknown fields or as a dynamic message, so the options are decoded
+// again with the extension types linked into the plugin.
+func getExtension(desc protoreflect.Descriptor, xt protoreflect.ExtensionType) interface{} {
+	opts := desc.Options()
+	if opts == nil || !opts.ProtoReflect().IsValid() {
+		return nil
+	}
+
+	b, err := proto.Marshal(opts)
+	if err != nil {
+		return nil
+	}
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(opts.ProtoReflect().Descriptor().FullName())
+	if err != nil {
+		return nil
+	}
+	m := mt.New().Interface()
+	if err := proto.Unmarshal(b, m); err != nil {
+		return nil
+	}
+	if !proto.HasExtension(m, xt) {
+		return nil
+	}
+
+	return proto.GetExtension(m, xt)
+}
@@ -0,0 +1,34 @@
+// Copyright 2019 The protoc-gen-jsonschema Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genjsonschema
+
+import (
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// applyFieldBehavior applies the "google.api.field_behavior" option of the field, which the APIs following the AIPs
+// annotate their fields with, to the schema t of the field and the message schema msgType.
+//
+// REQUIRED requires the field, OUTPUT_ONLY and INPUT_ONLY mark the schema "readOnly" and "writeOnly", and IMMUTABLE,
+// which has no JSON Schema equivalent, is written in the "x-immutable" extension. The other behaviors are left out.
+func (c *converter) applyFieldBehavior(msgType, t *Type, field protoreflect.FieldDescriptor) {
+	behaviors, _ := getExtension(field, annotations.E_FieldBehavior).([]annotations.FieldBehavior)
+	for _, behavior := range behaviors {
+		switch behavior {
+		case annotations.FieldBehavior_REQUIRED:
+			c.require(msgType, field)
+		case annotations.FieldBehavior_OUTPUT_ONLY:
+			t.ReadOnly = true
+		case annotations.FieldBehavior_INPUT_ONLY:
+			t.WriteOnly = true
+		case annotations.FieldBehavior_IMMUTABLE:
+			if t.Extensions == nil {
+				t.Extensions = make(map[string]interface{})
+			}
+			t.Extensions["x-immutable"] = true
+		}
+	}
+}
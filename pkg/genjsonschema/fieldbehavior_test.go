@@ -0,0 +1,91 @@
+// Copyright 2019 The protoc-gen-jsonschema Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genjsonschema
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/api/annotations"
+)
+
+func TestGenFieldBehavior(t *testing.T) {
+	file := fileDescriptorProto(t, `
+name: "books.proto" package: "test.books" syntax: "proto3"
+dependency: "google/api/field_behavior.proto"
+options { go_package: "example.com/test/books" }
+message_type {
+  name: "Book"
+  field { name: "name" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "name" options { [google.api.field_behavior]: [IDENTIFIER, IMMUTABLE] } }
+  field { name: "title" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "title" options { [google.api.field_behavior]: REQUIRED } }
+  field { name: "create_time" number: 3 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "createTime" options { [google.api.field_behavior]: OUTPUT_ONLY } }
+  field { name: "password" number: 4 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "password" options { [google.api.field_behavior]: [INPUT_ONLY, REQUIRED] } }
+  field { name: "author" number: 5 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "author" options { [google.api.field_behavior]: [REQUIRED, OUTPUT_ONLY] } }
+}`)
+	files := append(linkedFiles(annotations.File_google_api_field_behavior_proto), file)
+
+	for _, tc := range []struct {
+		parameter string
+		prefix    string
+	}{
+		// "readOnly" and "writeOnly" are the extensions before draft-07
+		{parameter: "", prefix: "x-"},
+		{parameter: "property_naming=both", prefix: "x-"},
+		{parameter: "draft=07"},
+	} {
+		parameter, prefix := tc.parameter, tc.prefix
+		t.Run(parameter, func(t *testing.T) {
+			def := definitions(t, generate(t, parameter, files...)["books.jsonschema"])["test.books.Book"]
+			properties := def["properties"].(map[string]interface{})
+
+			tests := []struct {
+				property string
+				keyword  string
+				want     interface{}
+			}{
+				{property: "name", keyword: "x-immutable", want: true},
+				{property: "name", keyword: prefix + "readOnly", want: nil},
+				{property: "create_time", keyword: prefix + "readOnly", want: true},
+				{property: "password", keyword: prefix + "writeOnly", want: true},
+				{property: "author", keyword: prefix + "readOnly", want: true},
+			}
+			for _, tt := range tests {
+				if got := properties[tt.property].(map[string]interface{})[tt.keyword]; got != tt.want {
+					t.Errorf("%s.%s = %v, want %v", tt.property, tt.keyword, got, tt.want)
+				}
+			}
+			if got := properties["create_time"].(map[string]interface{})["readOnly"]; prefix != "" && got != nil {
+				t.Errorf("create_time.readOnly = %v, want nil", got)
+			}
+
+			if parameter == "property_naming=both" {
+				return
+			}
+			want := []interface{}{"title", "password", "author"}
+			if got := def["required"]; !reflect.DeepEqual(got, want) {
+				t.Errorf("required = %v, want %v", got, want)
+			}
+		})
+	}
+
+	t.Run("validate", func(t *testing.T) {
+		schema := generate(t, "property_naming=both", files...)["books.jsonschema"]
+		tests := []struct {
+			document string
+			valid    bool
+		}{
+			{document: `{"title": "a", "password": "b", "author": "c"}`, valid: true},
+			{document: `{"title": "a", "password": "b", "author": "c", "createTime": "d"}`, valid: true},
+			{document: `{"password": "b", "author": "c"}`, valid: false},
+			{document: `{"title": "a", "author": "c"}`, valid: false},
+		}
+		for _, tt := range tests {
+			errs := validate(t, schema, "test.books.Book", tt.document)
+			if valid := len(errs) == 0; valid != tt.valid {
+				t.Errorf("%s: valid = %t, want %t: %v", tt.document, valid, tt.valid, errs)
+			}
+		}
+	})
+}
@@ -0,0 +1,187 @@
+// Copyright 2019 The protoc-gen-jsonschema Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genjsonschema
+
+import (
+	"encoding/json"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// OneofEncoding is an encoding of the oneof groups.
+type OneofEncoding string
+
+// list of the encodings of the oneof groups.
+const (
+	// OneofEncodingStrict encodes each oneof group as the "oneOf" of its members, or none of them.
+	OneofEncodingStrict OneofEncoding = "strict"
+	// OneofEncodingLenient encodes each oneof group as the "dependencies" which forbid the other members,
+	// for the editors which render "oneOf" badly.
+	OneofEncodingLenient OneofEncoding = "lenient"
+)
+
+// PropertyNaming is a naming of the message properties.
+type PropertyNaming string
+
+// list of the namings of the message properties.
+const (
+	// PropertyNamingProto names the properties by the proto field names, as protojson emits with UseProtoNames.
+	PropertyNamingProto PropertyNaming = "proto"
+	// PropertyNamingJSON names the properties by the json_name of the fields, as protojson emits by default.
+	PropertyNamingJSON PropertyNaming = "json"
+	// PropertyNamingBoth accepts either of the names but not both at once, as protojson accepts on input.
+	PropertyNamingBoth PropertyNaming = "both"
+)
+
+// PropertyOrder is an order of the message properties.
+type PropertyOrder string
+
+// list of the orders of the message properties.
+const (
+	// PropertyOrderDeclaration writes the properties in the declaration order of the fields.
+	PropertyOrderDeclaration PropertyOrder = "declaration"
+	// PropertyOrderAlphabetical writes the properties in the alphabetical order of their names.
+	PropertyOrderAlphabetical PropertyOrder = "alphabetical"
+)
+
+// Options configures the conversion of the Generator.
+//
+// The zero value converts as the plugin does without parameters: Draft04, OneofEncodingStrict, PropertyNamingProto
+// and PropertyOrderDeclaration.
+type Options struct {
+	AllowNullValues              bool
+	DisallowAdditionalProperties bool
+	DisallowBigIntsAsStrings     bool
+	OneofEncoding                OneofEncoding
+	PropertyNaming               PropertyNaming
+	PropertyOrder                PropertyOrder
+	CommentTitle                 bool
+	StripCommentDirectives       bool
+	Draft                        Draft
+
+	// Parallelism is the maximum number of the conversions running at once, of the files and messages by GenFiles and
+	// of the messages of each file, GOMAXPROCS if it is not positive.
+	Parallelism int
+
+	// Files resolves the message types of the fields. It must have the files to convert and their imports, and is
+	// only read, so it can be shared by the concurrent conversions. If nil, each conversion builds the registry of the
+	// file and the files it imports transitively.
+	Files *protoregistry.Files
+
+	limiter limiter // shared by the conversions of GenFiles, nil for the limiter of each conversion
+}
+
+// files returns the registry which resolves the message types of the fields of fds.
+func (opts Options) files(fds ...protoreflect.FileDescriptor) (*protoregistry.Files, error) {
+	if opts.Files != nil {
+		return opts.Files, nil
+	}
+	return newFiles(fds...)
+}
+
+// Generator converts proto descriptors into JSON Schema documents, independently of protoc.
+//
+// A Generator is safe for concurrent use, because the conversions share no state but the registry of the files, which
+// is only read.
+//
+// The descriptors can come from anywhere, such as the generated Go packages, protodesc, or a compiler such as
+// protocompile. They need the SourceCodeInfo for the descriptions.
+type Generator struct {
+	opts Options
+}
+
+// NewGenerator returns the Generator which converts with opts.
+func NewGenerator(opts Options) *Generator {
+	return &Generator{opts: opts}
+}
+
+// Schema is a JSON Schema document generated by the Generator.
+//
+// The embedded Type is the root schema, whose definitions hold the converted messages and enums keyed by their
+// fully-qualified names. Schema marshals into JSON in the keywords of the draft of the Options.
+type Schema struct {
+	*Type
+
+	emitter *emitter
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.emitter.root(s.Type))
+}
+
+// Message returns the JSON Schema document of the message, whose root refers to the definition of md.
+//
+// The definitions hold md and the messages and enums it refers to.
+func (g *Generator) Message(md protoreflect.MessageDescriptor) (*Schema, error) {
+	files, err := g.opts.files(md.ParentFile())
+	if err != nil {
+		return nil, err
+	}
+
+	c := newConverter(g.opts, files)
+	c.defineMessages([]protoreflect.MessageDescriptor{md})
+	if err := c.err(); err != nil {
+		return nil, err
+	}
+
+	return g.schema(&Type{
+		Ref:         string(md.FullName()),
+		Definitions: c.definitions,
+	}), nil
+}
+
+// File returns the JSON Schema document of the file, whose root accepts any of the top-level messages, or the
+// top-level enums if the file has no messages.
+//
+// The definitions hold all the messages and enums of the file, and the ones they refer to. The ones hidden by the
+// jsonschema options are left out unless the others refer to them, and a hidden file has no definitions.
+func (g *Generator) File(fd protoreflect.FileDescriptor) (*Schema, error) {
+	files, err := g.opts.files(fd)
+	if err != nil {
+		return nil, err
+	}
+
+	c := newConverter(g.opts, files)
+	c.defineFile(fd)
+	if err := c.err(); err != nil {
+		return nil, err
+	}
+
+	schema := &Type{
+		Title:       fileTitle(fd),
+		Definitions: c.definitions,
+	}
+	// the root is the "anyOf" rather than the "oneOf" of them, as a document of one message usually matches the others
+	// too, e.g. {} matches every message without the required fields
+	switch messages := fd.Messages(); {
+	case hidden(fd):
+		// the hidden file has no definitions to refer to
+	case messages.Len() == 0:
+		enums := fd.Enums()
+		for i := 0; i < enums.Len(); i++ {
+			if !hidden(enums.Get(i)) {
+				schema.AnyOf = append(schema.AnyOf, &Type{Ref: string(enums.Get(i).FullName())})
+			}
+		}
+	default:
+		for i := 0; i < messages.Len(); i++ {
+			if !hidden(messages.Get(i)) {
+				schema.AnyOf = append(schema.AnyOf, &Type{Ref: string(messages.Get(i).FullName())})
+			}
+		}
+	}
+
+	return g.schema(schema), nil
+}
+
+// schema returns the Schema of the root schema t.
+func (g *Generator) schema(t *Type) *Schema {
+	return &Schema{
+		Type:    t,
+		emitter: newEmitter(&options{Options: g.opts}),
+	}
+}
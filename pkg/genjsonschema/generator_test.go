@@ -0,0 +1,196 @@
+// Copyright 2019 The protoc-gen-jsonschema Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genjsonschema
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// compileFiles compiles the .proto files in the directory into the registry of the files and their dependencies.
+func compileFiles(t *testing.T, dir string, names ...string) *protoregistry.Files {
+	t.Helper()
+
+	files, err := protodesc.NewFiles(&descriptorpb.FileDescriptorSet{File: compileProtos(t, dir, names...)})
+	if err != nil {
+		t.Fatalf("protodesc.NewFiles: %v", err)
+	}
+
+	return files
+}
+
+// marshalIndent encodes the schema as the plugin does with the default parameters.
+func marshalIndent(t *testing.T, schema *Schema) string {
+	t.Helper()
+
+	b, err := json.MarshalIndent(schema, "", "    ")
+	if err != nil {
+		t.Fatalf("json.MarshalIndent: %v", err)
+	}
+
+	return string(b) + "\n"
+}
+
+func TestGeneratorFile(t *testing.T) {
+	protos, err := filepath.Glob(filepath.Join(goldenDir, "*.proto"))
+	if err != nil {
+		t.Fatalf("filepath.Glob: %v", err)
+	}
+
+	for _, proto := range protos {
+		name := filepath.Base(proto)
+		t.Run(name, func(t *testing.T) {
+			fd, err := compileFiles(t, goldenDir, name).FindFileByPath(name)
+			if err != nil {
+				t.Fatalf("FindFileByPath: %v", err)
+			}
+
+			schema, err := NewGenerator(Options{}).File(fd)
+			if err != nil {
+				t.Fatalf("File: %v", err)
+			}
+
+			// the zero Options converts as the plugin does without parameters
+			golden := filepath.Join(goldenDir, "default", strings.TrimSuffix(name, ".proto")+".jsonschema")
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("os.ReadFile: %v", err)
+			}
+			if diff := diffLines(marshalIndent(t, schema), string(want)); diff != "" {
+				t.Errorf("the schema differs from %s:\n%s", golden, diff)
+			}
+		})
+	}
+}
+
+func TestGeneratorMessage(t *testing.T) {
+	desc, err := compileFiles(t, goldenDir, "messages.proto").FindDescriptorByName("golden.messages.Node")
+	if err != nil {
+		t.Fatalf("FindDescriptorByName: %v", err)
+	}
+
+	tests := map[string]struct {
+		opts       Options
+		wantSchema string
+		wantRef    string
+		defsKey    string
+	}{
+		"default": {
+			opts:       Options{},
+			wantSchema: "http://json-schema.org/draft-04/schema#",
+			wantRef:    "#/definitions/golden.messages.Node",
+			defsKey:    "definitions",
+		},
+		"2020-12": {
+			opts:       Options{Draft: Draft202012, PropertyNaming: PropertyNamingJSON},
+			wantSchema: "https://json-schema.org/draft/2020-12/schema",
+			wantRef:    "#/$defs/golden.messages.Node",
+			defsKey:    "$defs",
+		},
+	}
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			schema, err := NewGenerator(tt.opts).Message(desc.(protoreflect.MessageDescriptor))
+			if err != nil {
+				t.Fatalf("Message: %v", err)
+			}
+
+			var root map[string]interface{}
+			if err := json.Unmarshal([]byte(marshalIndent(t, schema)), &root); err != nil {
+				t.Fatalf("json.Unmarshal: %v", err)
+			}
+			if got := root["$schema"]; got != tt.wantSchema {
+				t.Errorf("$schema = %v, want %s", got, tt.wantSchema)
+			}
+			if got := root["$ref"]; got != tt.wantRef {
+				t.Errorf("$ref = %v, want %s", got, tt.wantRef)
+			}
+
+			// the definitions hold only the message and the ones it refers to
+			defs, _ := root[tt.defsKey].(map[string]interface{})
+			if len(defs) != 1 || defs["golden.messages.Node"] == nil {
+				t.Errorf("%s = %v, want only golden.messages.Node", tt.defsKey, defs)
+			}
+		})
+	}
+}
+
+func TestGeneratorGeneratedDescriptors(t *testing.T) {
+	// the descriptors of the generated Go packages have no SourceCodeInfo
+	md := (&descriptorpb.FieldDescriptorProto{}).ProtoReflect().Descriptor()
+
+	schema, err := NewGenerator(Options{}).Message(md)
+	if err != nil {
+		t.Fatalf("Message: %v", err)
+	}
+	for _, name := range []string{
+		"google.protobuf.FieldDescriptorProto",
+		"google.protobuf.FieldDescriptorProto.Type",
+		"google.protobuf.FieldOptions",
+	} {
+		if _, ok := schema.Definitions[name]; !ok {
+			t.Errorf("%s is not defined", name)
+		}
+	}
+}
+
+func TestGeneratorConcurrent(t *testing.T) {
+	protos, err := filepath.Glob(filepath.Join(goldenDir, "*.proto"))
+	if err != nil {
+		t.Fatalf("filepath.Glob: %v", err)
+	}
+
+	g := NewGenerator(Options{AllowNullValues: true})
+	var fds []protoreflect.FileDescriptor
+	want := make(map[string]string)
+	for _, proto := range protos {
+		name := filepath.Base(proto)
+		fd, err := compileFiles(t, goldenDir, name).FindFileByPath(name)
+		if err != nil {
+			t.Fatalf("FindFileByPath: %v", err)
+		}
+		schema, err := g.File(fd)
+		if err != nil {
+			t.Fatalf("File: %v", err)
+		}
+		fds = append(fds, fd)
+		want[name] = marshalIndent(t, schema)
+	}
+
+	// the files share the imports such as scalars.proto and the well-known types, which are resolved concurrently
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		for _, fd := range fds {
+			wg.Add(1)
+			go func(fd protoreflect.FileDescriptor) {
+				defer wg.Done()
+				schema, err := g.File(fd)
+				if err != nil {
+					t.Errorf("File: %v", err)
+					return
+				}
+				b, err := json.MarshalIndent(schema, "", "    ")
+				if err != nil {
+					t.Errorf("json.MarshalIndent: %v", err)
+					return
+				}
+				if got := string(b) + "\n"; got != want[fd.Path()] {
+					t.Errorf("%s differs from the sequential conversion:\n%s", fd.Path(), diffLines(got, want[fd.Path()]))
+				}
+			}(fd)
+		}
+	}
+	wg.Wait()
+}
@@ -5,20 +5,17 @@
 package genjsonschema
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"path"
+	"slices"
+	"strconv"
 	"strings"
-	"sync"
 
-	"github.com/alecthomas/jsonschema"
-	descriptorpb "github.com/golang/protobuf/v2/types/descriptor"
-	pluginpb "github.com/golang/protobuf/v2/types/plugin"
 	"github.com/xeipuuv/gojsonschema"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
-	"google.golang.org/protobuf/proto"
-	"google.golang.org/protobuf/protogen"
+	"google.golang.org/protobuf/compiler/protogen"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/reflect/protoregistry"
 )
@@ -44,350 +41,451 @@ func init() {
 	log = l.Named("genjsonschema").Sugar()
 }
 
-type fileinfo struct {
-	*protogen.File
+// converter converts the proto descriptors into the definitions of the schemas.
+type converter struct {
+	files *protoregistry.Files // the files the message types of the fields are resolved in
 
-	allEnums         []*protogen.Enum
-	allEnumsByPtr    map[*protogen.Enum]int // value is index into allEnums
-	allMessages      []*protogen.Message
-	allMessagesByPtr map[*protogen.Message]int // value is index into allMessages
+	definitions Definitions
+	seen        map[string]bool // fully-qualified names of the messages whose conversion is started
+	referred    map[string]bool // fully-qualified names of the messages other converters define, only read
+	errs        []error
 
-	opts *options
+	opts Options
 }
 
-type options struct {
-	allowNullValues              bool
-	disallowAdditionalProperties bool
-	disallowBigIntsAsStrings     bool
-	debug                        bool
+// newConverter returns the converter with the empty definitions, which resolves the message types in files.
+func newConverter(opts Options, files *protoregistry.Files) *converter {
+	return &converter{
+		files:       files,
+		definitions: make(Definitions),
+		seen:        make(map[string]bool),
+		opts:        opts,
+	}
 }
 
-func Gen(gen *protogen.Plugin, file *protogen.File, g *protogen.GeneratedFile) {
-	defer log.Sync()
+// err returns the errors recorded during the conversion joined, or nil if there is none.
+func (c *converter) err() error {
+	return errors.Join(c.errs...)
+}
 
-	f := &fileinfo{
-		File: file,
-		opts: new(options),
-	}
+// options is the parameters of the plugin.
+type options struct {
+	Options
 
-	if parameter := gen.Request.GetParameter(); parameter != "" {
-		for _, param := range strings.Split(parameter, ",") {
-			parts := strings.Split(param, "=")
-			if len(parts) > 2 {
-				log.Warnf("invalid parameter: %q", param)
-				continue
-			}
+	outputFormat string
+	encoding     string
+	indent       int // negative for the default of the encoding
+	debug        bool
+}
 
-			switch parts[0] {
-			case "allow_null_values":
-				f.opts.allowNullValues = true
-			case "debug":
-				f.opts.debug = true
-				atom.SetLevel(zap.DebugLevel)
-			case "disallow_additional_properties":
-				f.opts.disallowAdditionalProperties = true
-			case "disallow_bigints_as_strings":
-				f.opts.disallowBigIntsAsStrings = true
-			default:
-				log.Warnf("unknown parameter: %q", param)
-			}
-		}
-	}
+// list of the formats of the output files.
+const (
+	// outputFormatJSONSchema writes a JSON Schema document for each proto file.
+	outputFormatJSONSchema = "jsonschema"
+	// outputFormatOpenAPI3 writes an OpenAPI 3.0 document for each proto package, which holds the schemas in its
+	// components.
+	outputFormatOpenAPI3 = "openapi3"
+	// outputFormatOpenAPI31 writes an OpenAPI 3.1 document for each proto package, which holds the schemas in its
+	// components.
+	outputFormatOpenAPI31 = "openapi3.1"
+)
 
-	f.allEnums = append(f.allEnums, f.Enums...)
-	f.allMessages = append(f.allMessages, f.Messages...)
-	walkMessages(f.Messages, func(m *protogen.Message) {
-		f.allEnums = append(f.allEnums, m.Enums...)
-		f.allMessages = append(f.allMessages, m.Messages...)
-	})
+// Gen generates the JSON Schema files for the file.
+//
+// The returned error reports all the elements of the file which failed to convert, and is meant to be
+// propagated into the CodeGeneratorResponse.error by protogen.
+func Gen(gen *protogen.Plugin, file *protogen.File) error {
+	defer log.Sync()
 
-	req := gen.Request
-	desc := file.Desc
+	opts := parseOptions(gen.Request.GetParameter())
 
-	resp, err := f.convertfn(req, desc)
+	out, err := renderFile(gen, file, opts)
 	if err != nil {
-		log.Fatalf("failed to convert proto to jsonschema: %v", err)
+		return err
+	}
+	if err := out.write(gen); err != nil {
+		return err
 	}
 
-	log.Debugf("resp: %v\n", resp)
+	log.Debug("succeeded to process code generator request")
+	return nil
+}
 
-	seen := make(map[string]bool)
-	rf := protoregistry.NewFiles(file.Desc)
-	rf.RangeFiles(func(fd protoreflect.FileDescriptor) bool {
-		for _, file := range req.GetFileToGenerate() {
-			seen[file] = true
-		}
-		return true
-	})
+// GenMessage generates the schema file of the message, whose root refers to the definition of the message, in the
+// directory of its proto file, e.g. "foo/v1/foo.v1.Bar.jsonschema".
+//
+// The message is converted with the parameters of gen as Gen does. The file of the message must be in gen.Files.
+func GenMessage(gen *protogen.Plugin, md protoreflect.MessageDescriptor) error {
+	defer log.Sync()
 
-	// if _, err := f.convert(gen.Request); err != nil {
-	// 	log.Fatalf("failed to convert proto to jsonschema: %+v", err)
-	// }
-
-	// log.Debugf("Gen", zap.Any("resp", resp))
-
-	// g := generator.New()
-	// g.Request = req
-	//
-	// if len(g.Request.FileToGenerate) == 0 {
-	// 	log.Fatal("no files to generate")
-	// }
-	//
-	// g.CommandLineParameters(g.Request.GetParameter())
-	// if parameter := g.Request.GetParameter(); parameter != "" {
-	// 	for _, param := range strings.Split(parameter, ",") {
-	// 		parts := strings.Split(param, "=")
-	// 		if len(parts) > 2 {
-	// 			log.Warnf("invalid parameter: %q", param)
-	// 			continue
-	// 		}
-	//
-	// 		switch parts[0] {
-	// 		case "allow_null_values":
-	// 			flagAllowNullValues = true
-	// 		case "debug":
-	// 			atom.SetLevel(zap.DebugLevel)
-	// 		case "disallow_additional_properties":
-	// 			flagDisallowAdditionalProperties = true
-	// 		case "disallow_bigints_as_strings":
-	// 			flagDisallowBigIntsAsStrings = true
-	// 		default:
-	// 			log.Warnf("unknown parameter: %q", param)
-	// 		}
-	// 	}
-	// }
-	//
-	// g.Response, err = convert(g.Request)
-	// if err != nil {
-	// 	log.Fatalf("failed to convert proto to jsonschema: %+v", err)
-	// }
-	//
-	// g.GenerateAllFiles()
-	//
-	// return resp, nil
-	log.Info("succeeded to process code generator request")
-}
+	opts := parseOptions(gen.Request.GetParameter())
 
-func (f *fileinfo) convertfn(req *pluginpb.CodeGeneratorRequest, desc protoreflect.FileDescriptor) (*pluginpb.CodeGeneratorResponse, error) {
-	seen := make(map[string]bool)
-	for _, gf := range req.GetFileToGenerate() {
-		seen[gf] = true
+	out, err := renderMessage(gen, md, opts)
+	if err != nil {
+		return err
 	}
 
-	return nil, nil
+	return out.write(gen)
 }
 
-func (f *fileinfo) convert(req *pluginpb.CodeGeneratorRequest) (*pluginpb.CodeGeneratorResponse, error) {
-	seenTargets := make(map[string]bool)
-	for _, file := range req.GetFileToGenerate() {
-		seenTargets[file] = true
+// renderFile converts the file into the content of its output file.
+//
+// It returns nil output for the files whose output is written with the other files, such as the OpenAPI documents
+// of the packages, and for the files hidden by their options.
+func renderFile(gen *protogen.Plugin, file *protogen.File, opts *options) (*output, error) {
+	if opts.outputFormat != outputFormatJSONSchema {
+		return renderOpenAPI(gen, file, opts)
+	}
+	if hidden(file.Desc) {
+		return nil, nil
 	}
 
-	resp := &pluginpb.CodeGeneratorResponse{}
-	for _, file := range req.GetProtoFile() {
-		for _, msg := range file.GetMessageType() {
-			log.Debugf("loading a message type %s from package %s", msg.GetName(), file.GetPackage())
-			registerType(file.Package, msg)
-		}
+	log.Debugf("converting file (%v)", file.Desc.Path())
+	schema, err := NewGenerator(opts.Options).File(file.Desc)
+	if err != nil {
+		return nil, err
 	}
-	for _, file := range req.GetProtoFile() {
-		if _, ok := seenTargets[file.GetName()]; ok {
-			log.Debugf("converting file (%v)", file.GetName())
-			converted, err := f.convertFile(file)
-			if err != nil {
-				resp.Error = proto.String(fmt.Sprintf("Failed to convert %s: %v", file.GetName(), err))
-				return resp, err
-			}
-			resp.File = append(resp.File, converted...)
-		}
+
+	jsonSchemaJSON, err := opts.marshal(schema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the JSON Schema of %s: %w", file.Desc.Path(), err)
 	}
 
-	return resp, nil
+	return &output{
+		fileName:     strings.TrimSuffix(file.Desc.Path(), path.Ext(file.Desc.Path())) + opts.extension(".jsonschema"),
+		goImportPath: file.GoImportPath,
+		content:      jsonSchemaJSON,
+	}, nil
 }
 
-// walkMessages calls f on each message and all of its descendants.
-func walkMessages(messages []*protogen.Message, f func(*protogen.Message)) {
-	for _, m := range messages {
-		f(m)
-		walkMessages(m.Messages, f)
+// renderMessage converts the message into the content of its output file.
+func renderMessage(gen *protogen.Plugin, md protoreflect.MessageDescriptor, opts *options) (*output, error) {
+	file, ok := gen.FilesByPath[md.ParentFile().Path()]
+	if !ok {
+		return nil, fmt.Errorf("no such file in the request: %s", md.ParentFile().Path())
 	}
-}
 
-// ProtoPackage describes a package of Protobuf, which is an container of message types.
-type ProtoPackage struct {
-	name     string
-	parent   *ProtoPackage
-	children map[string]*ProtoPackage
-	types    map[string]*descriptorpb.DescriptorProto
-}
+	log.Debugf("converting message (%v)", md.FullName())
+	schema, err := NewGenerator(opts.Options).Message(md)
+	if err != nil {
+		return nil, err
+	}
 
-var (
-	globalPkg = &ProtoPackage{
-		name:     "",
-		parent:   nil,
-		children: make(map[string]*ProtoPackage),
-		types:    make(map[string]*descriptorpb.DescriptorProto),
+	fileName := path.Join(path.Dir(file.Desc.Path()), string(md.FullName()))
+	var v interface{} = schema
+	switch opts.outputFormat {
+	case outputFormatJSONSchema:
+		fileName += opts.extension(".jsonschema")
+	default:
+		fileName += opts.extension(".openapi.json")
+		v = newEmitter(opts).document(string(md.FullName()), schema.Definitions)
 	}
 
-	globalPkgMu sync.RWMutex
-)
+	schemaJSON, err := opts.marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the schema of %s: %w", md.FullName(), err)
+	}
 
-func registerType(pkgName *string, msg *descriptorpb.DescriptorProto) {
-	globalPkgMu.RLock()
-	defer globalPkgMu.RUnlock()
+	return &output{
+		fileName:     fileName,
+		goImportPath: file.GoImportPath,
+		content:      schemaJSON,
+	}, nil
+}
 
-	log.Debugf("pkgName: %s\n", *pkgName)
-	pkg := globalPkg
-	if pkgName != nil {
-		for _, node := range strings.Split(*pkgName, ".") {
-			if pkg == globalPkg && node == "" {
-				// skips leading "."
-				continue
+// parseOptions parses the comma-separated parameter of the plugin.
+func parseOptions(parameter string) *options {
+	opts := &options{
+		Options: Options{
+			OneofEncoding:  OneofEncodingStrict,
+			PropertyNaming: PropertyNamingProto,
+			PropertyOrder:  PropertyOrderDeclaration,
+		},
+		outputFormat: outputFormatJSONSchema,
+		encoding:     encodingJSON,
+		indent:       -1,
+	}
+	if parameter == "" {
+		return opts
+	}
+
+	for _, param := range strings.Split(parameter, ",") {
+		parts := strings.Split(param, "=")
+		if len(parts) > 2 {
+			log.Warnf("invalid parameter: %q", param)
+			continue
+		}
+
+		switch parts[0] {
+		case "allow_null_values":
+			opts.AllowNullValues = boolParameter(parts, opts.AllowNullValues)
+		case "comment_title":
+			opts.CommentTitle = boolParameter(parts, opts.CommentTitle)
+		case "debug":
+			opts.debug = boolParameter(parts, opts.debug)
+			if opts.debug {
+				atom.SetLevel(zap.DebugLevel)
 			}
-			child, ok := pkg.children[node]
+		case "disallow_additional_properties":
+			opts.DisallowAdditionalProperties = boolParameter(parts, opts.DisallowAdditionalProperties)
+		case "disallow_bigints_as_strings":
+			opts.DisallowBigIntsAsStrings = boolParameter(parts, opts.DisallowBigIntsAsStrings)
+		case "draft":
+			value := parts[len(parts)-1]
+			d, ok := drafts[value]
 			if !ok {
-				child = &ProtoPackage{
-					name:     pkg.name + "." + node,
-					parent:   pkg,
-					children: make(map[string]*ProtoPackage),
-					types:    make(map[string]*descriptorpb.DescriptorProto),
-				}
-				pkg.children[node] = child
+				log.Warnf("unknown draft: %q", value)
+				continue
 			}
-			pkg = child
+			opts.Draft = d
+		case "encoding":
+			switch value := parts[len(parts)-1]; value {
+			case encodingJSON, encodingYAML:
+				opts.encoding = value
+			default:
+				log.Warnf("unknown encoding: %q", value)
+			}
+		case "indent":
+			value := parts[len(parts)-1]
+			indent, err := strconv.Atoi(value)
+			if err != nil || indent < 0 {
+				log.Warnf("invalid indent: %q", value)
+				continue
+			}
+			opts.indent = indent
+		case "oneof_encoding":
+			switch value := OneofEncoding(parts[len(parts)-1]); value {
+			case OneofEncodingStrict, OneofEncodingLenient:
+				opts.OneofEncoding = value
+			default:
+				log.Warnf("unknown oneof_encoding: %q", value)
+			}
+		case "output_format":
+			switch value := parts[len(parts)-1]; value {
+			case outputFormatJSONSchema, outputFormatOpenAPI3, outputFormatOpenAPI31:
+				opts.outputFormat = value
+			default:
+				log.Warnf("unknown output_format: %q", value)
+			}
+		case "parallelism":
+			value := parts[len(parts)-1]
+			parallelism, err := strconv.Atoi(value)
+			if err != nil || parallelism < 1 {
+				log.Warnf("invalid parallelism: %q", value)
+				continue
+			}
+			opts.Parallelism = parallelism
+		case "property_order":
+			switch value := PropertyOrder(parts[len(parts)-1]); value {
+			case PropertyOrderDeclaration, PropertyOrderAlphabetical:
+				opts.PropertyOrder = value
+			default:
+				log.Warnf("unknown property_order: %q", value)
+			}
+		case "strip_comment_directives":
+			opts.StripCommentDirectives = boolParameter(parts, opts.StripCommentDirectives)
+		case "property_naming":
+			switch value := PropertyNaming(parts[len(parts)-1]); value {
+			case PropertyNamingProto, PropertyNamingJSON, PropertyNamingBoth:
+				opts.PropertyNaming = value
+			default:
+				log.Warnf("unknown property_naming: %q", value)
+			}
+		default:
+			log.Warnf("unknown parameter: %q", param)
 		}
 	}
-	pkg.types[msg.GetName()] = msg
+
+	if opts.encoding == encodingYAML && opts.indent >= 0 && opts.indent < 2 {
+		// yaml.v3 replaces the indentations narrower than 2 by its own default of 4 rather than writing them
+		log.Warnf("invalid indent of yaml: %d, which must be 2 or more", opts.indent)
+		opts.indent = -1
+	}
+
+	// the schemas of OpenAPI are in the dialect of the specific draft
+	switch opts.outputFormat {
+	case outputFormatOpenAPI3:
+		opts.Draft = Draft04
+	case outputFormatOpenAPI31:
+		opts.Draft = Draft202012
+	}
+
+	return opts
+}
+
+// boolParameter returns the value of the boolean parameter split into parts, which is true without a value as in
+// "allow_null_values", or current if the value is invalid.
+func boolParameter(parts []string, current bool) bool {
+	if len(parts) == 1 {
+		return true
+	}
+	b, err := strconv.ParseBool(parts[1])
+	if err != nil {
+		log.Warnf("invalid %s: %q", parts[0], parts[1])
+		return current
+	}
+
+	return b
 }
 
-func relativelyLookupNestedType(desc *descriptorpb.DescriptorProto, name string) (*descriptorpb.DescriptorProto, bool) {
-	components := strings.Split(name, ".")
-componentLoop:
-	for _, component := range components {
-		for _, nested := range desc.GetNestedType() {
-			if nested.GetName() == component {
-				desc = nested
-				continue componentLoop
+// newFiles returns the registry of the files and the files they import transitively, which the message types of the
+// fields are resolved in.
+//
+// The registry is built for each conversion, so that the conversions share no state and can run concurrently.
+func newFiles(fds ...protoreflect.FileDescriptor) (*protoregistry.Files, error) {
+	files := new(protoregistry.Files)
+	var register func(fd protoreflect.FileDescriptor) error
+	register = func(fd protoreflect.FileDescriptor) error {
+		if _, err := files.FindFileByPath(fd.Path()); err == nil {
+			return nil
+		}
+
+		imports := fd.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			if err := register(imports.Get(i).FileDescriptor); err != nil {
+				return err
 			}
 		}
-		log.Warnf("no such nested message %s in %s", component, desc.GetName())
-		return nil, false
+		log.Debugf("loading file %s of package %s", fd.Path(), fd.Package())
+		if err := files.RegisterFile(fd); err != nil {
+			return &conversionError{desc: fd, err: err}
+		}
+
+		return nil
+	}
+	for _, fd := range fds {
+		if err := register(fd); err != nil {
+			return nil, err
+		}
 	}
 
-	return desc, true
+	return files, nil
 }
 
-func (pkg *ProtoPackage) relativelyLookupType(name string) (*descriptorpb.DescriptorProto, bool) {
-	components := strings.SplitN(name, ".", 2)
-	switch len(components) {
-	case 0:
-		log.Debug("empty message name")
-		return nil, false
-	case 1:
-		found, ok := pkg.types[components[0]]
-		return found, ok
-	case 2:
-		log.Debugf("looking for %s in %s at %s (%v)", components[1], components[0], pkg.name, pkg)
-		if child, ok := pkg.children[components[0]]; ok {
-			found, ok := child.relativelyLookupType(components[1])
-			return found, ok
-		}
-		if msg, ok := pkg.types[components[0]]; ok {
-			found, ok := relativelyLookupNestedType(msg, components[1])
-			return found, ok
-		}
-		log.Infof("no such package nor message %s in %s", components[0], pkg.name)
-		return nil, false
-	default:
-		log.Fatal("not reached")
-		return nil, false
+// resolveMessage returns the message type of the field from the files of the conversion.
+func (c *converter) resolveMessage(field protoreflect.FieldDescriptor) (protoreflect.MessageDescriptor, error) {
+	name := field.Message().FullName()
+	desc, err := c.files.FindDescriptorByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("no such message type named %s", name)
 	}
+	msg, ok := desc.(protoreflect.MessageDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a message type", name)
+	}
+
+	return msg, nil
 }
 
-func (pkg *ProtoPackage) relativelyLookupPackage(name string) (*ProtoPackage, bool) {
-	components := strings.Split(name, ".")
-	for _, c := range components {
-		var ok bool
-		pkg, ok = pkg.children[c]
-		if !ok {
-			return nil, false
-		}
+// defineMessage adds the definition of the message to the definitions if it is not defined yet.
+//
+// The message is marked as seen before converting its fields, so recursive references to the message
+// are emitted as a $ref to the definition which is being converted.
+func (c *converter) defineMessage(msg protoreflect.MessageDescriptor) error {
+	name := string(msg.FullName())
+	if c.seen[name] || c.referred[name] {
+		return nil
 	}
+	c.seen[name] = true
 
-	return pkg, true
-}
+	messageJSONSchema, err := c.convertMessageType(msg)
+	if err != nil {
+		return err
+	}
+	c.definitions[name] = &messageJSONSchema
 
-func (pkg *ProtoPackage) lookupType(name string) (*descriptorpb.DescriptorProto, bool) {
-	globalPkgMu.RLock()
-	defer globalPkgMu.RUnlock()
+	return nil
+}
 
-	if strings.HasPrefix(name, ".") {
-		return globalPkg.relativelyLookupType(name[1:])
+// defineEnum adds the definition of the enum to the definitions if it is not defined yet.
+func (c *converter) defineEnum(enum protoreflect.EnumDescriptor) error {
+	name := string(enum.FullName())
+	if _, ok := c.definitions[name]; ok {
+		return nil
 	}
 
-	for ; pkg != nil; pkg = pkg.parent {
-		if desc, ok := pkg.relativelyLookupType(name); ok {
-			return desc, ok
-		}
+	enumJSONSchema, err := c.convertEnumType(enum)
+	if err != nil {
+		return err
 	}
+	c.definitions[name] = &enumJSONSchema
 
-	return nil, false
+	return nil
 }
 
 // convertEnumType converts a proto "ENUM" into a JSON-Schema.
-func convertEnumType(enum *descriptorpb.EnumDescriptorProto) (jsonschema.Type, error) {
-	jsonSchemaType := jsonschema.Type{
-		Version: jsonschema.Version,
-	}
+func (c *converter) convertEnumType(enum protoreflect.EnumDescriptor) (Type, error) {
+	jsonSchemaType := Type{}
+
+	jsonSchemaType.OneOf = append(jsonSchemaType.OneOf, &Type{Type: "string"})
+	jsonSchemaType.OneOf = append(jsonSchemaType.OneOf, &Type{Type: "integer"})
 
-	jsonSchemaType.OneOf = append(jsonSchemaType.OneOf, &jsonschema.Type{Type: "string"})
-	jsonSchemaType.OneOf = append(jsonSchemaType.OneOf, &jsonschema.Type{Type: "integer"})
+	var described bool
+	seenNumbers := make(map[protoreflect.EnumNumber]bool)
+	values := enum.Values()
+	for i := 0; i < values.Len(); i++ {
+		enumValue := values.Get(i)
+		if hidden(enumValue) {
+			continue
+		}
+		description := c.commentsText(enumValue)
+		described = described || description != ""
+
+		jsonSchemaType.Enum = append(jsonSchemaType.Enum, enumValue.Name())
+		jsonSchemaType.EnumDescriptions = append(jsonSchemaType.EnumDescriptions, description)
 
-	for _, enumValue := range enum.Value {
-		jsonSchemaType.Enum = append(jsonSchemaType.Enum, enumValue.Name)
-		jsonSchemaType.Enum = append(jsonSchemaType.Enum, enumValue.Number)
+		// the aliases of allow_alias share the number, and the elements of "enum" must be unique
+		if seenNumbers[enumValue.Number()] {
+			continue
+		}
+		seenNumbers[enumValue.Number()] = true
+		jsonSchemaType.Enum = append(jsonSchemaType.Enum, enumValue.Number())
+		jsonSchemaType.EnumDescriptions = append(jsonSchemaType.EnumDescriptions, description)
 	}
+	if !described {
+		jsonSchemaType.EnumDescriptions = nil
+	}
+
+	c.describe(&jsonSchemaType, enum)
+	c.applyEnumOptions(&jsonSchemaType, enum)
 
 	return jsonSchemaType, nil
 }
 
-// alias of descriptor.FieldDescriptorProto_TYPE.
+// alias of protoreflect.Kind.
 const (
-	ProtoTypeBool     = descriptorpb.FieldDescriptorProto_TYPE_BOOL
-	ProtoTypeBytes    = descriptorpb.FieldDescriptorProto_TYPE_BYTES
-	ProtoTypeDouble   = descriptorpb.FieldDescriptorProto_TYPE_DOUBLE
-	ProtoTypeEnum     = descriptorpb.FieldDescriptorProto_TYPE_ENUM
-	ProtoTypeFixed32  = descriptorpb.FieldDescriptorProto_TYPE_FIXED32
-	ProtoTypeFixed64  = descriptorpb.FieldDescriptorProto_TYPE_FIXED64
-	ProtoTypeFloat    = descriptorpb.FieldDescriptorProto_TYPE_FLOAT
-	ProtoTypeGroup    = descriptorpb.FieldDescriptorProto_TYPE_GROUP
-	ProtoTypeInt32    = descriptorpb.FieldDescriptorProto_TYPE_INT32
-	ProtoTypeInt64    = descriptorpb.FieldDescriptorProto_TYPE_INT64
-	ProtoTypeMessage  = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
-	ProtoTypeSfixed32 = descriptorpb.FieldDescriptorProto_TYPE_SFIXED32
-	ProtoTypeSfixed64 = descriptorpb.FieldDescriptorProto_TYPE_SFIXED64
-	ProtoTypeSint32   = descriptorpb.FieldDescriptorProto_TYPE_SINT32
-	ProtoTypeSint64   = descriptorpb.FieldDescriptorProto_TYPE_SINT64
-	ProtoTypeString   = descriptorpb.FieldDescriptorProto_TYPE_STRING
-	ProtoTypeUint32   = descriptorpb.FieldDescriptorProto_TYPE_UINT32
-	ProtoTypeUint64   = descriptorpb.FieldDescriptorProto_TYPE_UINT64
-)
-
-var (
-	keyTrue  = []byte("true")
-	keyFalse = []byte("false")
+	ProtoTypeBool     = protoreflect.BoolKind
+	ProtoTypeBytes    = protoreflect.BytesKind
+	ProtoTypeDouble   = protoreflect.DoubleKind
+	ProtoTypeEnum     = protoreflect.EnumKind
+	ProtoTypeFixed32  = protoreflect.Fixed32Kind
+	ProtoTypeFixed64  = protoreflect.Fixed64Kind
+	ProtoTypeFloat    = protoreflect.FloatKind
+	ProtoTypeGroup    = protoreflect.GroupKind
+	ProtoTypeInt32    = protoreflect.Int32Kind
+	ProtoTypeInt64    = protoreflect.Int64Kind
+	ProtoTypeMessage  = protoreflect.MessageKind
+	ProtoTypeSfixed32 = protoreflect.Sfixed32Kind
+	ProtoTypeSfixed64 = protoreflect.Sfixed64Kind
+	ProtoTypeSint32   = protoreflect.Sint32Kind
+	ProtoTypeSint64   = protoreflect.Sint64Kind
+	ProtoTypeString   = protoreflect.StringKind
+	ProtoTypeUint32   = protoreflect.Uint32Kind
+	ProtoTypeUint64   = protoreflect.Uint64Kind
 )
 
 // convertField convert a proto "field".
-func (f *fileinfo) convertField(pkg *ProtoPackage, desc *descriptorpb.FieldDescriptorProto, dp *descriptorpb.DescriptorProto) (*jsonschema.Type, error) {
-	jsonSchemaType := &jsonschema.Type{
-		Properties: make(map[string]*jsonschema.Type),
+func (c *converter) convertField(field protoreflect.FieldDescriptor) (*Type, error) {
+	if field.IsMap() {
+		return c.convertMapField(field)
 	}
 
-	switch desc.GetType() {
+	jsonSchemaType := &Type{}
+
+	switch field.Kind() {
 	case ProtoTypeDouble, ProtoTypeFloat:
-		if f.opts.allowNullValues {
-			jsonSchemaType.OneOf = []*jsonschema.Type{
+		if c.opts.AllowNullValues {
+			jsonSchemaType.OneOf = []*Type{
 				{Type: gojsonschema.TYPE_NULL},
 				{Type: gojsonschema.TYPE_NUMBER},
 			}
@@ -396,8 +494,8 @@ func (f *fileinfo) convertField(pkg *ProtoPackage, desc *descriptorpb.FieldDescr
 		}
 
 	case ProtoTypeInt32, ProtoTypeUint32, ProtoTypeFixed32, ProtoTypeSfixed32, ProtoTypeSint32:
-		if f.opts.allowNullValues {
-			jsonSchemaType.OneOf = []*jsonschema.Type{
+		if c.opts.AllowNullValues {
+			jsonSchemaType.OneOf = []*Type{
 				{Type: gojsonschema.TYPE_NULL},
 				{Type: gojsonschema.TYPE_INTEGER},
 			}
@@ -406,18 +504,17 @@ func (f *fileinfo) convertField(pkg *ProtoPackage, desc *descriptorpb.FieldDescr
 		}
 
 	case ProtoTypeInt64, ProtoTypeUint64, ProtoTypeFixed64, ProtoTypeSfixed64, ProtoTypeSint64:
-		jsonSchemaType.OneOf = append(jsonSchemaType.OneOf, &jsonschema.Type{Type: gojsonschema.TYPE_INTEGER})
-		if !f.opts.disallowBigIntsAsStrings {
-			jsonSchemaType.OneOf = append(jsonSchemaType.OneOf, &jsonschema.Type{Type: gojsonschema.TYPE_STRING})
+		jsonSchemaType.OneOf = append(jsonSchemaType.OneOf, &Type{Type: gojsonschema.TYPE_INTEGER})
+		if !c.opts.DisallowBigIntsAsStrings {
+			jsonSchemaType.OneOf = append(jsonSchemaType.OneOf, &Type{Type: gojsonschema.TYPE_STRING})
 		}
-		if f.opts.allowNullValues {
-			jsonSchemaType.OneOf = append(jsonSchemaType.OneOf, &jsonschema.Type{Type: gojsonschema.TYPE_NULL})
+		if c.opts.AllowNullValues {
+			jsonSchemaType.OneOf = append(jsonSchemaType.OneOf, &Type{Type: gojsonschema.TYPE_NULL})
 		}
 
-	case ProtoTypeString,
-		descriptorpb.FieldDescriptorProto_TYPE_BYTES:
-		if f.opts.allowNullValues {
-			jsonSchemaType.OneOf = []*jsonschema.Type{
+	case ProtoTypeString, ProtoTypeBytes:
+		if c.opts.AllowNullValues {
+			jsonSchemaType.OneOf = []*Type{
 				{Type: gojsonschema.TYPE_NULL},
 				{Type: gojsonschema.TYPE_STRING},
 			}
@@ -426,26 +523,28 @@ func (f *fileinfo) convertField(pkg *ProtoPackage, desc *descriptorpb.FieldDescr
 		}
 
 	case ProtoTypeEnum:
-		jsonSchemaType.OneOf = append(jsonSchemaType.OneOf, &jsonschema.Type{Type: gojsonschema.TYPE_STRING})
-		jsonSchemaType.OneOf = append(jsonSchemaType.OneOf, &jsonschema.Type{Type: gojsonschema.TYPE_INTEGER})
-		if f.opts.allowNullValues {
-			jsonSchemaType.OneOf = append(jsonSchemaType.OneOf, &jsonschema.Type{Type: gojsonschema.TYPE_NULL})
+		if field.Enum().FullName() == "google.protobuf.NullValue" {
+			// the only value of google.protobuf.NullValue is represented as null
+			jsonSchemaType.Type = gojsonschema.TYPE_NULL
+			break
 		}
 
-		for _, enumDescriptor := range dp.GetEnumType() {
-			for _, enumValue := range enumDescriptor.Value {
-				fullFieldName := fmt.Sprintf(".%s.%s", *dp.Name, *enumDescriptor.Name)
+		if err := c.defineEnum(field.Enum()); err != nil {
+			return nil, err
+		}
 
-				if strings.HasSuffix(desc.GetTypeName(), fullFieldName) {
-					jsonSchemaType.Enum = append(jsonSchemaType.Enum, enumValue.Name)
-					jsonSchemaType.Enum = append(jsonSchemaType.Enum, enumValue.Number)
-				}
+		if c.opts.AllowNullValues {
+			jsonSchemaType.OneOf = []*Type{
+				{Type: gojsonschema.TYPE_NULL},
+				{Ref: string(field.Enum().FullName())},
 			}
+		} else {
+			jsonSchemaType.Ref = string(field.Enum().FullName())
 		}
 
 	case ProtoTypeBool:
-		if f.opts.allowNullValues {
-			jsonSchemaType.OneOf = []*jsonschema.Type{
+		if c.opts.AllowNullValues {
+			jsonSchemaType.OneOf = []*Type{
 				{Type: gojsonschema.TYPE_NULL},
 				{Type: gojsonschema.TYPE_BOOLEAN},
 			}
@@ -455,74 +554,120 @@ func (f *fileinfo) convertField(pkg *ProtoPackage, desc *descriptorpb.FieldDescr
 
 	case ProtoTypeGroup, ProtoTypeMessage:
 		jsonSchemaType.Type = gojsonschema.TYPE_OBJECT
-		if desc.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL {
-			jsonSchemaType.AdditionalProperties = keyTrue
-		}
-		if desc.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REQUIRED {
-			jsonSchemaType.AdditionalProperties = keyFalse
-		}
 
 	default:
-		return nil, fmt.Errorf("unrecognized field type: %s", desc.GetType().String())
+		return nil, fmt.Errorf("unrecognized field type: %s", field.Kind().String())
 	}
 
-	if desc.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REPEATED && jsonSchemaType.Type != gojsonschema.TYPE_OBJECT {
-		jsonSchemaType.Items = &jsonschema.Type{
+	if field.Cardinality() == protoreflect.Repeated && jsonSchemaType.Type != gojsonschema.TYPE_OBJECT {
+		jsonSchemaType.Items = &Type{
+			Ref:   jsonSchemaType.Ref,
 			Type:  jsonSchemaType.Type,
 			OneOf: jsonSchemaType.OneOf,
 		}
-		if f.opts.allowNullValues {
-			jsonSchemaType.OneOf = []*jsonschema.Type{
+		jsonSchemaType.Ref = ""
+		if c.opts.AllowNullValues {
+			jsonSchemaType.OneOf = []*Type{
 				{Type: gojsonschema.TYPE_NULL},
 				{Type: gojsonschema.TYPE_ARRAY},
 			}
 		} else {
 			jsonSchemaType.Type = gojsonschema.TYPE_ARRAY
-			jsonSchemaType.OneOf = []*jsonschema.Type{}
+			jsonSchemaType.OneOf = []*Type{}
 		}
 
 		return jsonSchemaType, nil
 	}
 
 	if jsonSchemaType.Type == gojsonschema.TYPE_OBJECT {
-		recordType, ok := pkg.lookupType(desc.GetTypeName())
-		if !ok {
-			return nil, fmt.Errorf("no such message type named %s", desc.GetTypeName())
+		recordType, err := c.resolveMessage(field)
+		if err != nil {
+			return nil, err
 		}
 
-		recursedJSONSchemaType, err := f.convertMessageType(pkg, recordType)
+		wellKnownJSONSchemaType, err := c.convertWellKnownType(recordType)
 		if err != nil {
 			return nil, err
 		}
+		elem := wellKnownJSONSchemaType
+		if elem == nil {
+			if err := c.defineMessage(recordType); err != nil {
+				return nil, err
+			}
+			elem = &Type{Ref: string(recordType.FullName())}
+		}
 
-		if desc.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REPEATED {
-			jsonSchemaType.Items = &recursedJSONSchemaType
+		switch {
+		case field.Cardinality() == protoreflect.Repeated:
+			jsonSchemaType.Items = elem
 			jsonSchemaType.Type = gojsonschema.TYPE_ARRAY
-		} else {
-			jsonSchemaType.Properties = recursedJSONSchemaType.Properties
-		}
 
-		if f.opts.allowNullValues {
-			jsonSchemaType.OneOf = []*jsonschema.Type{
-				{Type: gojsonschema.TYPE_NULL},
-				{Type: jsonSchemaType.Type},
+			if c.opts.AllowNullValues {
+				jsonSchemaType.OneOf = []*Type{
+					{Type: gojsonschema.TYPE_NULL},
+					{Type: jsonSchemaType.Type},
+				}
+				jsonSchemaType.Type = ""
 			}
-			jsonSchemaType.Type = ""
+		case wellKnownJSONSchemaType != nil && c.opts.AllowNullValues:
+			jsonSchemaType = nullable(wellKnownJSONSchemaType)
+		default:
+			// the definitions of the messages accept null by themselves with allow_null_values, and wrapping the
+			// reference into the "oneOf" with null would make null match both of them
+			//
+			// the siblings of "$ref" are ignored until 2019-09, and constrain the referred schema since then
+			jsonSchemaType = elem
 		}
 	}
 
 	return jsonSchemaType, nil
 }
 
-// convertMessageType converts a proto "MESSAGE" into a JSON-Schema.
-func (f *fileinfo) convertMessageType(pkg *ProtoPackage, msg *descriptorpb.DescriptorProto) (jsonschema.Type, error) {
-	jsonSchemaType := jsonschema.Type{
-		Properties: make(map[string]*jsonschema.Type),
-		Version:    jsonschema.Version,
+// mapKeyPatterns is the patterns of the map keys, which are encoded as JSON strings, for each integer key kinds.
+var mapKeyPatterns = map[protoreflect.Kind]string{
+	ProtoTypeInt32:    `^-?(0|[1-9][0-9]*)$`,
+	ProtoTypeSint32:   `^-?(0|[1-9][0-9]*)$`,
+	ProtoTypeSfixed32: `^-?(0|[1-9][0-9]*)$`,
+	ProtoTypeInt64:    `^-?(0|[1-9][0-9]*)$`,
+	ProtoTypeSint64:   `^-?(0|[1-9][0-9]*)$`,
+	ProtoTypeSfixed64: `^-?(0|[1-9][0-9]*)$`,
+	ProtoTypeUint32:   `^(0|[1-9][0-9]*)$`,
+	ProtoTypeFixed32:  `^(0|[1-9][0-9]*)$`,
+	ProtoTypeUint64:   `^(0|[1-9][0-9]*)$`,
+	ProtoTypeFixed64:  `^(0|[1-9][0-9]*)$`,
+}
+
+// convertMapField converts a proto "map<K,V>" field into a JSON object keyed by the string form of K.
+func (c *converter) convertMapField(field protoreflect.FieldDescriptor) (*Type, error) {
+	keyField, valueField := field.MapKey(), field.MapValue()
+
+	valueJSONSchemaType, err := c.convertField(valueField)
+	if err != nil {
+		return nil, err
+	}
+	jsonSchemaType := &Type{
+		AdditionalProperties: valueJSONSchemaType,
 	}
 
-	if f.opts.allowNullValues {
-		jsonSchemaType.OneOf = []*jsonschema.Type{
+	switch kind := keyField.Kind(); kind {
+	case ProtoTypeString:
+		// any string is a valid key
+	case ProtoTypeBool:
+		jsonSchemaType.PropertyNames = &Type{
+			Enum: []interface{}{"true", "false"},
+		}
+	default:
+		pattern, ok := mapKeyPatterns[kind]
+		if !ok {
+			return nil, fmt.Errorf("unrecognized map key type: %s", kind.String())
+		}
+		jsonSchemaType.PropertyNames = &Type{
+			Pattern: pattern,
+		}
+	}
+
+	if c.opts.AllowNullValues {
+		jsonSchemaType.OneOf = []*Type{
 			{Type: gojsonschema.TYPE_NULL},
 			{Type: gojsonschema.TYPE_OBJECT},
 		}
@@ -530,90 +675,252 @@ func (f *fileinfo) convertMessageType(pkg *ProtoPackage, msg *descriptorpb.Descr
 		jsonSchemaType.Type = gojsonschema.TYPE_OBJECT
 	}
 
-	if f.opts.disallowAdditionalProperties {
-		jsonSchemaType.AdditionalProperties = keyFalse
+	return jsonSchemaType, nil
+}
+
+// convertMessageType converts a proto "MESSAGE" into a JSON-Schema.
+func (c *converter) convertMessageType(msg protoreflect.MessageDescriptor) (Type, error) {
+	jsonSchemaType := Type{
+		Properties: &Properties{},
+	}
+
+	if c.opts.AllowNullValues {
+		jsonSchemaType.OneOf = []*Type{
+			{Type: gojsonschema.TYPE_NULL},
+			{Type: gojsonschema.TYPE_OBJECT},
+		}
 	} else {
-		jsonSchemaType.AdditionalProperties = keyTrue
+		jsonSchemaType.Type = gojsonschema.TYPE_OBJECT
 	}
 
-	// log.Debugf("Converting message: %s", proto.MarshalTextString(msg))
-	for _, fieldDesc := range msg.GetField() {
-		recursedJSONSchemaType, err := f.convertField(pkg, fieldDesc, msg)
+	disallowAdditionalProperties := c.additionalPropertiesDisallowed(msg)
+	switch {
+	case disallowAdditionalProperties && c.opts.Draft >= Draft201909:
+		// unlike "additionalProperties", it also sees the properties evaluated by the subschemas
+		jsonSchemaType.UnevaluatedProperties = boolSchema(false)
+	case disallowAdditionalProperties:
+		jsonSchemaType.AdditionalProperties = boolSchema(false)
+	default:
+		jsonSchemaType.AdditionalProperties = boolSchema(true)
+	}
+
+	validated := !validateDisabled(msg)
+	fields := msg.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		if hidden(field) {
+			continue
+		}
+		recursedJSONSchemaType, err := c.convertField(field)
 		if err != nil {
-			log.Errorf("Failed to convert field %s in %s: %v", fieldDesc.GetName(), msg.GetName(), err)
-			return jsonSchemaType, err
+			c.addError(field, err)
+			continue
+		}
+		c.describe(recursedJSONSchemaType, field)
+		if field.Cardinality() == protoreflect.Required {
+			// the proto2 required fields, and the fields of LEGACY_REQUIRED field_presence in the editions
+			c.require(&jsonSchemaType, field)
+		}
+		var unsupported []string
+		if validated {
+			unsupported = c.applyValidateRules(&jsonSchemaType, recursedJSONSchemaType, field)
+		}
+		unsupported = append(unsupported, c.applyProtovalidateRules(&jsonSchemaType, recursedJSONSchemaType, field)...)
+		if len(unsupported) > 0 {
+			log.Warnf("ignoring the validation rules of %s which have no JSON Schema equivalent: %s", field.FullName(), strings.Join(unsupported, ", "))
+		}
+		c.applyFieldBehavior(&jsonSchemaType, recursedJSONSchemaType, field)
+		if err := c.applyFieldOptions(&jsonSchemaType, recursedJSONSchemaType, field); err != nil {
+			c.addError(field, err)
+			continue
+		}
+
+		names := c.propertyNames(field)
+		for _, name := range names {
+			jsonSchemaType.Properties.Set(name, recursedJSONSchemaType)
+		}
+		if len(names) > 1 {
+			jsonSchemaType.AllOf = append(jsonSchemaType.AllOf, &Type{
+				Not: &Type{Required: names},
+			})
 		}
-		jsonSchemaType.Properties[fieldDesc.GetName()] = recursedJSONSchemaType
+	}
+
+	oneofs := msg.Oneofs()
+	for i := 0; i < oneofs.Len(); i++ {
+		oneof := oneofs.Get(i)
+		if oneof.IsSynthetic() {
+			// proto3 optional fields are not mutually exclusive
+			continue
+		}
+		fields := oneofFields(oneof)
+		if len(fields) == 0 {
+			continue
+		}
+		oneofRequired := (validated && validateOneofRequired(oneof)) || protovalidateOneofRequired(oneof)
+		c.convertOneof(fields, &jsonSchemaType, oneofRequired)
+	}
+
+	c.describe(&jsonSchemaType, msg)
+	c.applyProtovalidateMessageRules(&jsonSchemaType, msg)
+	if err := c.applyMessageOptions(&jsonSchemaType, msg); err != nil {
+		return Type{}, err
 	}
 
 	return jsonSchemaType, nil
 }
 
-// convertFile converts a proto file into a JSON-Schema.
-func (f *fileinfo) convertFile(file *descriptorpb.FileDescriptorProto) (resp []*pluginpb.CodeGeneratorResponse_File, err error) {
-	protoFileName := path.Base(file.GetName())
+// propertyNames returns the names of the property of the field in the message schema.
+func (c *converter) propertyNames(field protoreflect.FieldDescriptor) []string {
+	// protojson names the groups by their message names with UseProtoNames, rather than the lowercased field names
+	protoName, jsonName := field.TextName(), field.JSONName()
 
-	switch len(file.GetMessageType()) {
-	case 0:
-		if len(file.GetEnumType()) > 1 {
-			log.Warnf("protoc-gen-jsonschema will create multiple ENUM schemas (%d) from one proto file (%s)", len(file.GetEnumType()), protoFileName)
+	switch c.opts.PropertyNaming {
+	case PropertyNamingJSON:
+		return []string{jsonName}
+	case PropertyNamingBoth:
+		if protoName != jsonName {
+			return []string{protoName, jsonName}
 		}
+	}
 
-		for _, enum := range file.GetEnumType() {
-			jsonSchemaFileName := fmt.Sprintf("%s.jsonschema", enum.GetName())
-			log.Infof("generating JSON-schema for stand-alone ENUM (%v) in file [%s] => %s", enum.GetName(), protoFileName, jsonSchemaFileName)
+	return []string{protoName}
+}
 
-			enumJSONSchema, err := convertEnumType(enum)
-			if err != nil {
-				log.Errorf("failed to convert %s: %v", protoFileName, err)
-				return nil, err
-			}
+// presenceOf returns the schema which requires the property of the field by any of its names.
+func (c *converter) presenceOf(field protoreflect.FieldDescriptor) *Type {
+	names := c.propertyNames(field)
+	if len(names) == 1 {
+		return &Type{Required: names}
+	}
 
-			jsonSchemaJSON, err := json.MarshalIndent(enumJSONSchema, "", "    ")
-			if err != nil {
-				log.Errorf("failed to encode jsonSchema: %v", err)
-				return nil, err
-			}
+	presence := &Type{}
+	for _, name := range names {
+		presence.AnyOf = append(presence.AnyOf, &Type{Required: []string{name}})
+	}
 
-			respFile := &pluginpb.CodeGeneratorResponse_File{
-				Name:    proto.String(jsonSchemaFileName),
-				Content: proto.String(string(jsonSchemaJSON)),
-			}
-			resp = append(resp, respFile)
+	return presence
+}
+
+// require adds the constraint which requires the property of the field to the message schema.
+//
+// The field may be required by several options, such as the validation rules and the field behaviors, and the
+// elements of "required" must be unique, so the properties already required are not added again.
+func (c *converter) require(jsonSchemaType *Type, field protoreflect.FieldDescriptor) {
+	presence := c.presenceOf(field)
+	if presence.AnyOf != nil {
+		jsonSchemaType.AllOf = append(jsonSchemaType.AllOf, presence)
+		return
+	}
+	for _, name := range presence.Required {
+		if !slices.Contains(jsonSchemaType.Required, name) {
+			jsonSchemaType.Required = append(jsonSchemaType.Required, name)
 		}
-	default:
-		log.Warnf("protoc-gen-jsonschema will create multiple MESSAGE schemas (%d) from one proto file (%s)", len(file.GetMessageType()), protoFileName)
+	}
+}
 
-		globalPkgMu.RLock()
-		pkg, ok := globalPkg.relativelyLookupPackage(file.GetPackage())
-		globalPkgMu.RUnlock()
-		if !ok {
-			return nil, fmt.Errorf("no such package found: %s", file.GetPackage())
+// oneofFields returns the fields of the oneof, except for the ones hidden by their options.
+func oneofFields(oneof protoreflect.OneofDescriptor) []protoreflect.FieldDescriptor {
+	fields := make([]protoreflect.FieldDescriptor, 0, oneof.Fields().Len())
+	for i := 0; i < oneof.Fields().Len(); i++ {
+		if field := oneof.Fields().Get(i); !hidden(field) {
+			fields = append(fields, field)
 		}
+	}
 
-		for _, msg := range file.GetMessageType() {
-			jsonSchemaFileName := fmt.Sprintf("%s.jsonschema", msg.GetName())
-			log.Debugf("generating JSON-schema for MESSAGE (%s) in file [%s] => %s", msg.GetName(), protoFileName, jsonSchemaFileName)
+	return fields
+}
 
-			messageJSONSchema, err := f.convertMessageType(pkg, msg)
-			if err != nil {
-				log.Errorf("failed to convert %s: %v", protoFileName, err)
-				return nil, err
-			}
+// convertOneof adds the constraints which allow at most one of the fields of a proto "oneof" to the message schema,
+// or exactly one of them if the oneof is required.
+func (c *converter) convertOneof(fields []protoreflect.FieldDescriptor, jsonSchemaType *Type, oneofRequired bool) {
+	required := make([]*Type, len(fields))
+	for i := range required {
+		required[i] = c.presenceOf(fields[i])
+	}
 
-			jsonSchemaJSON, err := json.MarshalIndent(messageJSONSchema, "", "    ")
-			if err != nil {
-				log.Errorf("failed to encode jsonSchema: %v", err)
-				return nil, err
+	switch {
+	case c.opts.OneofEncoding == OneofEncodingLenient:
+		if jsonSchemaType.DependentSchemas == nil {
+			jsonSchemaType.DependentSchemas = make(map[string]*Type)
+		}
+		for i := range required {
+			others := make([]*Type, 0, len(required)-1)
+			others = append(others, required[:i]...)
+			others = append(others, required[i+1:]...)
+			if len(others) == 0 {
+				continue
 			}
-
-			respFile := &pluginpb.CodeGeneratorResponse_File{
-				Name:    proto.String(jsonSchemaFileName),
-				Content: proto.String(string(jsonSchemaJSON)),
+			for _, name := range c.propertyNames(fields[i]) {
+				jsonSchemaType.DependentSchemas[name] = &Type{
+					Not: &Type{AnyOf: others},
+				}
 			}
-			resp = append(resp, respFile)
 		}
+		if oneofRequired {
+			jsonSchemaType.AllOf = append(jsonSchemaType.AllOf, &Type{AnyOf: required})
+		}
+	case oneofRequired:
+		jsonSchemaType.AllOf = append(jsonSchemaType.AllOf, &Type{OneOf: required})
+	default:
+		jsonSchemaType.AllOf = append(jsonSchemaType.AllOf, &Type{
+			OneOf: append(required, &Type{
+				Not: &Type{AnyOf: required},
+			}),
+		})
+	}
+}
+
+// defineFile adds the definitions of all the messages and enums of the file, and the ones they refer to.
+//
+// The errors are recorded rather than returned, so that all the failing elements of the files are reported at once.
+// The file, messages and enums hidden by their options are left out, unless the others refer to them.
+func (c *converter) defineFile(fd protoreflect.FileDescriptor) {
+	if hidden(fd) {
+		return
 	}
+	walkFile(fd, func(enum protoreflect.EnumDescriptor) {
+		if hidden(enum) {
+			return
+		}
+		log.Debugf("generating JSON-schema for ENUM (%s) in file [%s]", enum.FullName(), fd.Path())
+		if err := c.defineEnum(enum); err != nil {
+			c.addError(enum, err)
+		}
+	}, nil)
+	var messages []protoreflect.MessageDescriptor
+	walkFile(fd, nil, func(msg protoreflect.MessageDescriptor) {
+		if msg.IsMapEntry() || hidden(msg) {
+			return
+		}
+		log.Debugf("generating JSON-schema for MESSAGE (%s) in file [%s]", msg.FullName(), fd.Path())
+		messages = append(messages, msg)
+	})
+	c.defineMessages(messages)
+}
 
-	return resp, nil
+// walkFile calls enumFunc on each enum and msgFunc on each message of the file, including the nested ones, in the
+// order the parents are visited before their children. Either of the functions may be nil.
+func walkFile(fd protoreflect.FileDescriptor, enumFunc func(protoreflect.EnumDescriptor), msgFunc func(protoreflect.MessageDescriptor)) {
+	type container interface {
+		Enums() protoreflect.EnumDescriptors
+		Messages() protoreflect.MessageDescriptors
+	}
+	var walk func(container)
+	walk = func(parent container) {
+		if enumFunc != nil {
+			enums := parent.Enums()
+			for i := 0; i < enums.Len(); i++ {
+				enumFunc(enums.Get(i))
+			}
+		}
+		messages := parent.Messages()
+		for i := 0; i < messages.Len(); i++ {
+			if msgFunc != nil {
+				msgFunc(messages.Get(i))
+			}
+			walk(messages.Get(i))
+		}
+	}
+	walk(fd)
 }
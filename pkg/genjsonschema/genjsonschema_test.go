@@ -0,0 +1,1205 @@
+// Copyright 2019 The protoc-gen-jsonschema Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genjsonschema
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xeipuuv/gojsonschema"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"google.golang.org/protobuf/types/pluginpb"
+	"gopkg.in/yaml.v3"
+)
+
+// generate runs Gen for the last file of files and returns the generated file contents keyed by file name.
+func generate(t *testing.T, parameter string, files ...*descriptorpb.FileDescriptorProto) map[string]string {
+	t.Helper()
+
+	return generateFiles(t, parameter, []string{files[len(files)-1].GetName()}, files...)
+}
+
+// generateFiles runs Gen for the files named fileToGenerate and returns the generated file contents keyed by file name.
+func generateFiles(t *testing.T, parameter string, fileToGenerate []string, files ...*descriptorpb.FileDescriptorProto) map[string]string {
+	t.Helper()
+
+	req := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: fileToGenerate,
+		Parameter:      proto.String(parameter),
+		ProtoFile:      files,
+	}
+	gen, err := protogen.Options{}.New(req)
+	if err != nil {
+		t.Fatalf("protogen.Options.New: %v", err)
+	}
+	for _, f := range gen.Files {
+		if f.Generate {
+			if err := Gen(gen, f); err != nil {
+				gen.Error(err)
+			}
+		}
+	}
+
+	resp := gen.Response()
+	if resp.Error != nil {
+		t.Fatalf("Gen: %s", resp.GetError())
+	}
+	out := make(map[string]string)
+	for _, f := range resp.GetFile() {
+		out[f.GetName()] = f.GetContent()
+	}
+
+	return out
+}
+
+// fileDescriptorProto parses the text-encoded FileDescriptorProto.
+func fileDescriptorProto(t *testing.T, s string) *descriptorpb.FileDescriptorProto {
+	t.Helper()
+
+	fd := &descriptorpb.FileDescriptorProto{}
+	if err := prototext.Unmarshal([]byte(s), fd); err != nil {
+		t.Fatalf("prototext.Unmarshal: %v", err)
+	}
+
+	return fd
+}
+
+// definitions decodes the generated schema and returns its definitions.
+func definitions(t *testing.T, schema string) map[string]map[string]interface{} {
+	t.Helper()
+
+	var root struct {
+		Definitions map[string]map[string]interface{} `json:"definitions"`
+	}
+	if err := json.Unmarshal([]byte(schema), &root); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	}
+
+	return root.Definitions
+}
+
+// schemaURL is the URL the generated schema is loaded from by definitionSchema.
+const schemaURL = "file:///schema.jsonschema"
+
+// definitionSchema compiles the reference to the definition named name in the generated schema.
+//
+// The schema is loaded as it is generated, so the root of the schema takes no part in the validation against it.
+func definitionSchema(t *testing.T, schema, name string) *gojsonschema.Schema {
+	t.Helper()
+
+	loader := gojsonschema.NewSchemaLoader()
+	if err := loader.AddSchema(schemaURL, gojsonschema.NewStringLoader(schema)); err != nil {
+		t.Fatalf("gojsonschema.SchemaLoader.AddSchema: %v", err)
+	}
+	ref, err := loader.Compile(gojsonschema.NewGoLoader(map[string]interface{}{"$ref": schemaURL + "#/definitions/" + name}))
+	if err != nil {
+		t.Fatalf("gojsonschema.SchemaLoader.Compile: %v", err)
+	}
+
+	return ref
+}
+
+// validate validates the JSON document against the definition named name in the generated schema.
+func validate(t *testing.T, schema, name, document string) []gojsonschema.ResultError {
+	t.Helper()
+
+	result, err := definitionSchema(t, schema, name).Validate(gojsonschema.NewStringLoader(document))
+	if err != nil {
+		t.Fatalf("gojsonschema.Schema.Validate: %v", err)
+	}
+
+	return result.Errors()
+}
+
+// validateRoot validates the JSON document against the root of the generated schema as it is.
+func validateRoot(t *testing.T, schema, document string) []gojsonschema.ResultError {
+	t.Helper()
+
+	result, err := gojsonschema.Validate(gojsonschema.NewStringLoader(schema), gojsonschema.NewStringLoader(document))
+	if err != nil {
+		t.Fatalf("gojsonschema.Validate: %v", err)
+	}
+
+	return result.Errors()
+}
+
+func TestGenFileRoot(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		valid   []string
+		invalid []string
+	}{
+		{
+			name: "messages",
+			file: `
+name: "apps.proto" package: "test.apps" syntax: "proto3"
+options { go_package: "example.com/test/apps" }
+message_type {
+  name: "Pod"
+  field { name: "name" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "name" }
+}
+message_type {
+  name: "Service"
+  field { name: "name" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "name" }
+  field { name: "port" number: 2 label: LABEL_OPTIONAL type: TYPE_INT32 json_name: "port" }
+}`,
+			// {"name": "web"} is both a Pod and a Service
+			valid:   []string{`{"name": "web"}`, `{"name": "web", "port": 80}`, `{}`},
+			invalid: []string{`{"name": 1}`, `"web"`},
+		},
+		{
+			name: "enums",
+			file: `
+name: "phases.proto" package: "test.phases" syntax: "proto3"
+options { go_package: "example.com/test/phases" }
+enum_type { name: "Phase" value { name: "PHASE_UNSPECIFIED" number: 0 } value { name: "RUNNING" number: 1 } }
+enum_type { name: "Condition" value { name: "CONDITION_UNSPECIFIED" number: 0 } }`,
+			// 0 is both a Phase and a Condition
+			valid:   []string{`"RUNNING"`, `"CONDITION_UNSPECIFIED"`, `0`},
+			invalid: []string{`"STOPPED"`, `2`},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			file := fileDescriptorProto(t, tt.file)
+			schema := generate(t, "", file)[strings.TrimSuffix(file.GetName(), path.Ext(file.GetName()))+".jsonschema"]
+
+			for _, document := range tt.valid {
+				if errs := validateRoot(t, schema, document); len(errs) > 0 {
+					t.Errorf("%s is not valid: %v", document, errs)
+				}
+			}
+			for _, document := range tt.invalid {
+				if errs := validateRoot(t, schema, document); len(errs) == 0 {
+					t.Errorf("%s must not be valid", document)
+				}
+			}
+		})
+	}
+}
+
+func TestGenRecursiveMessages(t *testing.T) {
+	tests := []struct {
+		name      string
+		deps      []*descriptorpb.FileDescriptorProto
+		file      string
+		wantRefs  map[string]string // "<definition>.<property>" => $ref of the property or its items
+		validates map[string]string // definition => JSON document which must be valid
+	}{
+		{
+			name: "direct",
+			file: `
+name: "tree.proto" package: "test.recursion" syntax: "proto3"
+options { go_package: "example.com/test/recursion" }
+message_type {
+  name: "Node"
+  field { name: "value" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "value" }
+  field { name: "parent" number: 2 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".test.recursion.Node" json_name: "parent" }
+  field { name: "children" number: 3 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".test.recursion.Node" json_name: "children" }
+}`,
+			wantRefs: map[string]string{
+				"test.recursion.Node.parent":   "#/definitions/test.recursion.Node",
+				"test.recursion.Node.children": "#/definitions/test.recursion.Node",
+			},
+			validates: map[string]string{
+				"test.recursion.Node": `{"value": "root", "children": [{"value": "leaf", "children": [{"parent": {"value": "root"}}]}]}`,
+			},
+		},
+		{
+			name: "indirect",
+			file: `
+name: "expr.proto" package: "test.recursion" syntax: "proto3"
+options { go_package: "example.com/test/recursion" }
+message_type {
+  name: "Expr"
+  field { name: "call" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".test.recursion.Call" json_name: "call" }
+  field { name: "ident" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "ident" }
+}
+message_type {
+  name: "Call"
+  field { name: "function" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "function" }
+  field { name: "args" number: 2 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".test.recursion.Expr" json_name: "args" }
+}`,
+			wantRefs: map[string]string{
+				"test.recursion.Expr.call": "#/definitions/test.recursion.Call",
+				"test.recursion.Call.args": "#/definitions/test.recursion.Expr",
+			},
+			validates: map[string]string{
+				"test.recursion.Expr": `{"call": {"function": "f", "args": [{"ident": "x"}, {"call": {"function": "g"}}]}}`,
+			},
+		},
+		{
+			name: "nested",
+			file: `
+name: "outer.proto" package: "test.recursion" syntax: "proto3"
+options { go_package: "example.com/test/recursion" }
+message_type {
+  name: "Outer"
+  field { name: "inner" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".test.recursion.Outer.Inner" json_name: "inner" }
+  nested_type {
+    name: "Inner"
+    field { name: "outer" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".test.recursion.Outer" json_name: "outer" }
+    field { name: "next" number: 2 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".test.recursion.Outer.Inner" json_name: "next" }
+  }
+}`,
+			wantRefs: map[string]string{
+				"test.recursion.Outer.inner":       "#/definitions/test.recursion.Outer.Inner",
+				"test.recursion.Outer.Inner.outer": "#/definitions/test.recursion.Outer",
+				"test.recursion.Outer.Inner.next":  "#/definitions/test.recursion.Outer.Inner",
+			},
+			validates: map[string]string{
+				"test.recursion.Outer": `{"inner": {"next": {"outer": {"inner": {}}}}}`,
+			},
+		},
+		{
+			name: "imported",
+			deps: []*descriptorpb.FileDescriptorProto{
+				fileDescriptorProto(t, `
+name: "list.proto" package: "test.deps" syntax: "proto3"
+options { go_package: "example.com/test/deps" }
+message_type {
+  name: "List"
+  field { name: "value" number: 1 label: LABEL_OPTIONAL type: TYPE_INT32 json_name: "value" }
+  field { name: "next" number: 2 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".test.deps.List" json_name: "next" }
+}`),
+			},
+			file: `
+name: "config.proto" package: "test.recursion" syntax: "proto3"
+dependency: "list.proto"
+options { go_package: "example.com/test/recursion" }
+message_type {
+  name: "Config"
+  field { name: "list" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".test.deps.List" json_name: "list" }
+}`,
+			wantRefs: map[string]string{
+				"test.recursion.Config.list": "#/definitions/test.deps.List",
+				"test.deps.List.next":        "#/definitions/test.deps.List",
+			},
+			validates: map[string]string{
+				"test.recursion.Config": `{"list": {"value": 1, "next": {"value": 2, "next": {}}}}`,
+			},
+		},
+		{
+			name: "package-less",
+			file: `
+name: "nopkg.proto" syntax: "proto3"
+options { go_package: "example.com/test/nopkg" }
+message_type {
+  name: "Root"
+  field { name: "leaf" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".Leaf" json_name: "leaf" }
+  field { name: "inner" number: 2 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".Root.Inner" json_name: "inner" }
+  nested_type {
+    name: "Inner"
+    field { name: "root" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".Root" json_name: "root" }
+  }
+}
+message_type {
+  name: "Leaf"
+  field { name: "value" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "value" }
+}`,
+			wantRefs: map[string]string{
+				"Root.leaf":       "#/definitions/Leaf",
+				"Root.inner":      "#/definitions/Root.Inner",
+				"Root.Inner.root": "#/definitions/Root",
+			},
+			validates: map[string]string{
+				"Root": `{"leaf": {"value": "x"}, "inner": {"root": {}}}`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			file := fileDescriptorProto(t, tt.file)
+			out := generate(t, "", append(tt.deps, file)...)
+
+			schema, ok := out[strings.TrimSuffix(file.GetName(), path.Ext(file.GetName()))+".jsonschema"]
+			if !ok {
+				t.Fatalf("no schema generated for %s: %v", file.GetName(), out)
+			}
+
+			defs := definitions(t, schema)
+			for key, want := range tt.wantRefs {
+				i := strings.LastIndexByte(key, '.')
+				def, prop := key[:i], key[i+1:]
+				properties, _ := defs[def]["properties"].(map[string]interface{})
+				property, ok := properties[prop].(map[string]interface{})
+				if !ok {
+					t.Fatalf("no property %s in definition %s", prop, def)
+				}
+				if items, ok := property["items"].(map[string]interface{}); ok {
+					property = items
+				}
+				if got := property["$ref"]; got != want {
+					t.Errorf("%s: $ref = %v, want %s", key, got, want)
+				}
+			}
+
+			for name, document := range tt.validates {
+				if errs := validate(t, schema, name, document); len(errs) > 0 {
+					t.Errorf("%s: %s is not valid: %v", name, document, errs)
+				}
+				// the documents are of the top-level messages, so the root accepts them too
+				if errs := validateRoot(t, schema, document); len(errs) > 0 {
+					t.Errorf("root: %s is not valid: %v", document, errs)
+				}
+			}
+		})
+	}
+}
+
+func TestGenMapFields(t *testing.T) {
+	file := fileDescriptorProto(t, `
+name: "maps.proto" package: "test.maps" syntax: "proto3"
+options { go_package: "example.com/test/maps" }
+message_type {
+  name: "Maps"
+  field { name: "labels" number: 1 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".test.maps.Maps.LabelsEntry" json_name: "labels" }
+  field { name: "counts" number: 2 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".test.maps.Maps.CountsEntry" json_name: "counts" }
+  field { name: "flags" number: 3 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".test.maps.Maps.FlagsEntry" json_name: "flags" }
+  field { name: "items" number: 4 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".test.maps.Maps.ItemsEntry" json_name: "items" }
+  nested_type {
+    name: "LabelsEntry" options { map_entry: true }
+    field { name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "key" }
+    field { name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "value" }
+  }
+  nested_type {
+    name: "CountsEntry" options { map_entry: true }
+    field { name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_INT64 json_name: "key" }
+    field { name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_INT32 json_name: "value" }
+  }
+  nested_type {
+    name: "FlagsEntry" options { map_entry: true }
+    field { name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_BOOL json_name: "key" }
+    field { name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_BOOL json_name: "value" }
+  }
+  nested_type {
+    name: "ItemsEntry" options { map_entry: true }
+    field { name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_UINT32 json_name: "key" }
+    field { name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".test.maps.Item" json_name: "value" }
+  }
+}
+message_type {
+  name: "Item"
+  field { name: "name" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "name" }
+}`)
+	for _, parameter := range []string{"", "draft=07"} {
+		parameter := parameter
+		t.Run(parameter, func(t *testing.T) {
+			schema := generate(t, parameter, file)["maps.jsonschema"]
+
+			defs := definitions(t, schema)
+			for name := range defs {
+				if strings.HasSuffix(name, "Entry") {
+					t.Errorf("map entry %s must not be defined", name)
+				}
+			}
+
+			properties := defs["test.maps.Maps"]["properties"].(map[string]interface{})
+			tests := []struct {
+				property          string
+				wantPropertyNames map[string]interface{}
+				wantPattern       string
+				wantValueType     string
+				wantValueRef      string
+			}{
+				{property: "labels", wantValueType: "string"},
+				{property: "counts", wantPropertyNames: map[string]interface{}{"pattern": `^-?(0|[1-9][0-9]*)$`}, wantPattern: `^-?(0|[1-9][0-9]*)$`, wantValueType: "integer"},
+				{property: "flags", wantPropertyNames: map[string]interface{}{"enum": []interface{}{"true", "false"}}, wantPattern: `^(true|false)$`, wantValueType: "boolean"},
+				{property: "items", wantPropertyNames: map[string]interface{}{"pattern": `^(0|[1-9][0-9]*)$`}, wantPattern: `^(0|[1-9][0-9]*)$`, wantValueRef: "#/definitions/test.maps.Item"},
+			}
+			for _, tt := range tests {
+				property := properties[tt.property].(map[string]interface{})
+				if got := property["type"]; got != "object" {
+					t.Errorf("%s: type = %v, want object", tt.property, got)
+				}
+
+				value, _ := property["additionalProperties"].(map[string]interface{})
+				if parameter == "" && tt.wantPattern != "" {
+					// draft-04 has no "propertyNames", so the keys are the pattern of the values
+					if got := property["additionalProperties"]; got != false {
+						t.Errorf("%s: additionalProperties = %v, want false", tt.property, got)
+					}
+					patternProperties, _ := property["patternProperties"].(map[string]interface{})
+					value, _ = patternProperties[tt.wantPattern].(map[string]interface{})
+					if value == nil {
+						t.Errorf("%s: patternProperties = %v, want the values of %s", tt.property, property["patternProperties"], tt.wantPattern)
+						continue
+					}
+				} else {
+					want := tt.wantPropertyNames
+					if parameter == "" {
+						want = nil
+					}
+					gotJSON, _ := json.Marshal(property["propertyNames"])
+					wantJSON, _ := json.Marshal(want)
+					if string(gotJSON) != string(wantJSON) {
+						t.Errorf("%s: propertyNames = %s, want %s", tt.property, gotJSON, wantJSON)
+					}
+				}
+				if got := value["type"]; tt.wantValueType != "" && got != tt.wantValueType {
+					t.Errorf("%s: type of the values = %v, want %s", tt.property, got, tt.wantValueType)
+				}
+				if got := value["$ref"]; tt.wantValueRef != "" && got != tt.wantValueRef {
+					t.Errorf("%s: $ref of the values = %v, want %s", tt.property, got, tt.wantValueRef)
+				}
+			}
+
+			valid := `{"labels": {"app": "web"}, "counts": {"-1": 2}, "flags": {"true": false}, "items": {"1": {"name": "x"}}}`
+			if errs := validate(t, schema, "test.maps.Maps", valid); len(errs) > 0 {
+				t.Errorf("%s is not valid: %v", valid, errs)
+			}
+			for _, invalid := range []string{
+				`{"labels": [{"key": "app", "value": "web"}]}`,
+				`{"counts": {"one": 1}}`,
+				`{"flags": {"yes": true}}`,
+			} {
+				if errs := validate(t, schema, "test.maps.Maps", invalid); len(errs) == 0 {
+					t.Errorf("%s must not be valid", invalid)
+				}
+			}
+		})
+	}
+}
+
+func TestGenOneofs(t *testing.T) {
+	file := fileDescriptorProto(t, `
+name: "oneofs.proto" package: "test.oneofs" syntax: "proto3"
+options { go_package: "example.com/test/oneofs" }
+message_type {
+  name: "Shape"
+  field { name: "circle" number: 1 label: LABEL_OPTIONAL type: TYPE_DOUBLE json_name: "circle" oneof_index: 0 }
+  field { name: "square" number: 2 label: LABEL_OPTIONAL type: TYPE_DOUBLE json_name: "square" oneof_index: 0 }
+  field { name: "name" number: 3 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "name" oneof_index: 2 proto3_optional: true }
+  field { name: "color" number: 4 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "color" oneof_index: 1 }
+  field { name: "rgb" number: 5 label: LABEL_OPTIONAL type: TYPE_UINT32 json_name: "rgb" oneof_index: 1 }
+  oneof_decl { name: "kind" }
+  oneof_decl { name: "fill" }
+  oneof_decl { name: "_name" }
+}`)
+
+	tests := []struct {
+		document string
+		valid    bool
+	}{
+		{document: `{}`, valid: true},
+		{document: `{"circle": 1}`, valid: true},
+		{document: `{"square": 1, "name": "s", "rgb": 255}`, valid: true},
+		{document: `{"circle": 1, "square": 1}`, valid: false},
+		{document: `{"circle": 1, "color": "red", "rgb": 255}`, valid: false},
+	}
+	for _, encoding := range []OneofEncoding{OneofEncodingStrict, OneofEncodingLenient} {
+		encoding := encoding
+		t.Run(string(encoding), func(t *testing.T) {
+			schema := generate(t, "oneof_encoding="+string(encoding), file)["oneofs.jsonschema"]
+
+			def := definitions(t, schema)["test.oneofs.Shape"]
+			switch encoding {
+			case OneofEncodingStrict:
+				if allOf, _ := def["allOf"].([]interface{}); len(allOf) != 2 {
+					t.Errorf("allOf = %v, want the constraints of 2 oneofs", def["allOf"])
+				}
+			case OneofEncodingLenient:
+				if _, ok := def["oneOf"]; ok {
+					t.Errorf("oneOf = %v, want none", def["oneOf"])
+				}
+				if dependencies, _ := def["dependencies"].(map[string]interface{}); len(dependencies) != 4 {
+					t.Errorf("dependencies = %v, want the constraints of 4 members", def["dependencies"])
+				}
+			}
+
+			for _, tt := range tests {
+				errs := validate(t, schema, "test.oneofs.Shape", tt.document)
+				if valid := len(errs) == 0; valid != tt.valid {
+					t.Errorf("%s: valid = %t, want %t: %v", tt.document, valid, tt.valid, errs)
+				}
+			}
+		})
+	}
+}
+
+func TestGenRequiredFields(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+	}{
+		{
+			name: "proto2",
+			file: `
+name: "required.proto" package: "test.required" syntax: "proto2"
+options { go_package: "example.com/test/required" }
+message_type {
+  name: "Order"
+  field { name: "id" number: 1 label: LABEL_REQUIRED type: TYPE_STRING json_name: "id" }
+  field { name: "item" number: 2 label: LABEL_REQUIRED type: TYPE_MESSAGE type_name: ".test.required.Item" json_name: "item" }
+  field { name: "note" number: 3 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "note" }
+}
+message_type { name: "Item" field { name: "sku" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "sku" } }`,
+		},
+		{
+			name: "editions",
+			file: `
+name: "required.proto" package: "test.required" syntax: "editions" edition: EDITION_2023
+options { go_package: "example.com/test/required" }
+message_type {
+  name: "Order"
+  field { name: "id" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "id" options { features { field_presence: LEGACY_REQUIRED } } }
+  field { name: "item" number: 2 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".test.required.Item" json_name: "item" options { features { field_presence: LEGACY_REQUIRED } } }
+  field { name: "note" number: 3 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "note" }
+}
+message_type { name: "Item" field { name: "sku" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "sku" } }`,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			schema := generate(t, "", fileDescriptorProto(t, tt.file))["required.jsonschema"]
+
+			def := definitions(t, schema)["test.required.Order"]
+			if got, want := def["required"], []interface{}{"id", "item"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("required = %v, want %v", got, want)
+			}
+
+			for document, valid := range map[string]bool{
+				`{"id": "1", "item": {"sku": "a", "extra": 1}}`: true,
+				`{"id": "1", "note": "n"}`:                      false,
+				`{"item": {}}`:                                  false,
+			} {
+				if errs := validate(t, schema, "test.required.Order", document); (len(errs) == 0) != valid {
+					t.Errorf("%s: valid = %t, want %t: %v", document, len(errs) == 0, valid, errs)
+				}
+			}
+		})
+	}
+}
+
+func TestGenPropertyNaming(t *testing.T) {
+	file := fileDescriptorProto(t, `
+name: "naming.proto" package: "test.naming" syntax: "proto3"
+options { go_package: "example.com/test/naming" }
+message_type {
+  name: "Pet"
+  field { name: "pet_id" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "petId" }
+  field { name: "name" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "name" }
+  field { name: "dog_breed" number: 3 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "dogBreed" oneof_index: 0 }
+  field { name: "cat_breed" number: 4 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "catBreed" oneof_index: 0 }
+  oneof_decl { name: "breed" }
+}`)
+
+	tests := []struct {
+		naming PropertyNaming
+		valid  []string
+		// invalid documents are checked with disallow_additional_properties.
+		invalid []string
+	}{
+		{
+			naming:  PropertyNamingProto,
+			valid:   []string{`{"pet_id": "1", "name": "a", "dog_breed": "pug"}`},
+			invalid: []string{`{"petId": "1"}`},
+		},
+		{
+			naming:  PropertyNamingJSON,
+			valid:   []string{`{"petId": "1", "name": "a", "catBreed": "sphynx"}`},
+			invalid: []string{`{"pet_id": "1"}`, `{"dogBreed": "pug", "catBreed": "sphynx"}`},
+		},
+		{
+			naming: PropertyNamingBoth,
+			valid: []string{
+				`{"pet_id": "1", "name": "a", "dog_breed": "pug"}`,
+				`{"petId": "1", "name": "a", "dogBreed": "pug"}`,
+				`{"petId": "1", "cat_breed": "sphynx"}`,
+			},
+			invalid: []string{
+				`{"pet_id": "1", "petId": "1"}`,
+				`{"dog_breed": "pug", "catBreed": "sphynx"}`,
+				`{"dog_breed": "pug", "dogBreed": "pug"}`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(string(tt.naming), func(t *testing.T) {
+			for _, encoding := range []OneofEncoding{OneofEncodingStrict, OneofEncodingLenient} {
+				parameter := "disallow_additional_properties,oneof_encoding=" + string(encoding) + ",property_naming=" + string(tt.naming)
+				schema := generate(t, parameter, file)["naming.jsonschema"]
+
+				for _, document := range tt.valid {
+					if errs := validate(t, schema, "test.naming.Pet", document); len(errs) > 0 {
+						t.Errorf("%s: %s is not valid: %v", encoding, document, errs)
+					}
+				}
+				for _, document := range tt.invalid {
+					if errs := validate(t, schema, "test.naming.Pet", document); len(errs) == 0 {
+						t.Errorf("%s: %s must not be valid", encoding, document)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestGenComments(t *testing.T) {
+	file := fileDescriptorProto(t, `
+name: "comments.proto" package: "test.comments" syntax: "proto3"
+options { go_package: "example.com/test/comments" }
+message_type {
+  name: "Pod"
+  field { name: "name" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "name" }
+  field { name: "phase" number: 2 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".test.comments.Phase" json_name: "phase" }
+}
+enum_type {
+  name: "Phase"
+  value { name: "PHASE_UNSPECIFIED" number: 0 }
+  value { name: "RUNNING" number: 1 }
+}
+source_code_info {
+  location { path: [4, 0] span: [0, 0, 0] leading_detached_comments: " Pods.\n" leading_comments: " Pod is a collection of containers. It is the smallest unit.\n buf:lint:ignore FIELD_LOWER_SNAKE_CASE\n" }
+  location { path: [4, 0, 2, 0] span: [0, 0, 0] leading_comments: " Name must be unique.\n" trailing_comments: " @exclude internal\n" }
+  location { path: [5, 0] span: [0, 0, 0] leading_comments: " Phase of the pod\n" }
+  location { path: [5, 0, 2, 1] span: [0, 0, 0] trailing_comments: " The pod is running.\n" }
+}`)
+
+	tests := []struct {
+		parameter string
+		want      map[string]map[string]interface{} // "<definition>[.<property>]" => title and description
+	}{
+		{
+			parameter: "",
+			want: map[string]map[string]interface{}{
+				"test.comments.Pod": {
+					"description": "Pods.\n\nPod is a collection of containers. It is the smallest unit.\nbuf:lint:ignore FIELD_LOWER_SNAKE_CASE",
+				},
+				"test.comments.Pod.name": {"description": "Name must be unique.\n\n@exclude internal"},
+				"test.comments.Phase":    {"description": "Phase of the pod"},
+			},
+		},
+		{
+			parameter: "comment_title=false,strip_comment_directives=false",
+			want: map[string]map[string]interface{}{
+				"test.comments.Pod": {
+					"description": "Pods.\n\nPod is a collection of containers. It is the smallest unit.\nbuf:lint:ignore FIELD_LOWER_SNAKE_CASE",
+				},
+				"test.comments.Pod.name": {"description": "Name must be unique.\n\n@exclude internal"},
+				"test.comments.Phase":    {"description": "Phase of the pod"},
+			},
+		},
+		{
+			parameter: "comment_title,strip_comment_directives",
+			want: map[string]map[string]interface{}{
+				"test.comments.Pod": {
+					"title":       "Pods",
+					"description": "Pod is a collection of containers. It is the smallest unit.",
+				},
+				"test.comments.Pod.name": {"title": "Name must be unique"},
+				"test.comments.Phase":    {"title": "Phase of the pod"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		schema := generate(t, tt.parameter, file)["comments.jsonschema"]
+		defs := definitions(t, schema)
+
+		for key, want := range tt.want {
+			got, ok := defs[key]
+			if !ok {
+				i := strings.LastIndexByte(key, '.')
+				got = defs[key[:i]]["properties"].(map[string]interface{})[key[i+1:]].(map[string]interface{})
+			}
+			for _, keyword := range []string{"title", "description"} {
+				if got[keyword] != want[keyword] {
+					t.Errorf("%q: %s: %s = %q, want %q", tt.parameter, key, keyword, got[keyword], want[keyword])
+				}
+			}
+		}
+
+		enumDescriptions, _ := json.Marshal(defs["test.comments.Phase"]["enumDescriptions"])
+		if want := `["","","The pod is running.","The pod is running."]`; string(enumDescriptions) != want {
+			t.Errorf("%q: enumDescriptions = %s, want %s", tt.parameter, enumDescriptions, want)
+		}
+	}
+}
+
+func TestGenWellKnownTypes(t *testing.T) {
+	var deps []*descriptorpb.FileDescriptorProto
+	for _, fd := range []protoreflect.FileDescriptor{
+		anypb.File_google_protobuf_any_proto,
+		durationpb.File_google_protobuf_duration_proto,
+		emptypb.File_google_protobuf_empty_proto,
+		fieldmaskpb.File_google_protobuf_field_mask_proto,
+		structpb.File_google_protobuf_struct_proto,
+		timestamppb.File_google_protobuf_timestamp_proto,
+		wrapperspb.File_google_protobuf_wrappers_proto,
+	} {
+		deps = append(deps, protodesc.ToFileDescriptorProto(fd))
+	}
+	file := fileDescriptorProto(t, `
+name: "wkt.proto" package: "test.wkt" syntax: "proto3"
+dependency: ["google/protobuf/any.proto", "google/protobuf/duration.proto", "google/protobuf/empty.proto", "google/protobuf/field_mask.proto", "google/protobuf/struct.proto", "google/protobuf/timestamp.proto", "google/protobuf/wrappers.proto"]
+options { go_package: "example.com/test/wkt" }
+message_type {
+  name: "Event"
+  field { name: "time" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Timestamp" json_name: "time" }
+  field { name: "timeout" number: 2 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Duration" json_name: "timeout" }
+  field { name: "mask" number: 3 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.FieldMask" json_name: "mask" }
+  field { name: "attributes" number: 4 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Struct" json_name: "attributes" }
+  field { name: "value" number: 5 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Value" json_name: "value" }
+  field { name: "values" number: 6 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.ListValue" json_name: "values" }
+  field { name: "detail" number: 7 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Any" json_name: "detail" }
+  field { name: "empty" number: 8 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Empty" json_name: "empty" }
+  field { name: "count" number: 9 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Int64Value" json_name: "count" }
+  field { name: "enabled" number: 10 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.BoolValue" json_name: "enabled" }
+  field { name: "history" number: 11 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".google.protobuf.Timestamp" json_name: "history" }
+}`)
+	schema := generate(t, "", append(deps, file)...)["wkt.jsonschema"]
+
+	for name := range definitions(t, schema) {
+		if strings.HasPrefix(name, "google.protobuf.") {
+			t.Errorf("well-known type %s must not be defined", name)
+		}
+	}
+
+	tests := []struct {
+		document string
+		valid    bool
+	}{
+		{
+			document: `{
+  "time": "1972-01-01T10:00:20.021Z",
+  "timeout": "1.5s",
+  "mask": "user.displayName,photo",
+  "attributes": {"a": 1, "b": [true, null]},
+  "value": "anything",
+  "values": [1, "two", {"three": 3}],
+  "detail": {"@type": "type.googleapis.com/google.protobuf.Duration", "value": "1s"},
+  "empty": {},
+  "count": "9007199254740993",
+  "enabled": null,
+  "history": ["2019-07-03T20:46:51Z"]
+}`,
+			valid: true,
+		},
+		{document: `{"value": null, "count": 1, "enabled": true}`, valid: true},
+		{document: `{"time": {"seconds": 1}}`, valid: false},
+		{document: `{"timeout": {"seconds": 1, "nanos": 5}}`, valid: false},
+		{document: `{"timeout": "1.5"}`, valid: false},
+		{document: `{"mask": {"paths": ["a"]}}`, valid: false},
+		{document: `{"detail": {"value": "1s"}}`, valid: false},
+		{document: `{"empty": {"a": 1}}`, valid: false},
+		{document: `{"enabled": {"value": true}}`, valid: false},
+		{document: `{"history": [{"seconds": 1}]}`, valid: false},
+	}
+	for _, tt := range tests {
+		errs := validate(t, schema, "test.wkt.Event", tt.document)
+		if valid := len(errs) == 0; valid != tt.valid {
+			t.Errorf("%s: valid = %t, want %t: %v", tt.document, valid, tt.valid, errs)
+		}
+	}
+}
+
+func TestGenEnumFields(t *testing.T) {
+	dep := fileDescriptorProto(t, `
+name: "levels.proto" package: "test.deps" syntax: "proto3"
+options { go_package: "example.com/test/deps" }
+enum_type { name: "Severity" value { name: "SEVERITY_UNSPECIFIED" number: 0 } value { name: "HIGH" number: 1 } }
+message_type {
+  name: "Log"
+  enum_type { name: "Level" value { name: "LEVEL_UNSPECIFIED" number: 0 } value { name: "DEBUG" number: 1 } }
+}`)
+	file := fileDescriptorProto(t, `
+name: "enums.proto" package: "test.enums" syntax: "proto3"
+dependency: ["levels.proto", "google/protobuf/struct.proto"]
+options { go_package: "example.com/test/enums" }
+enum_type {
+  name: "State"
+  options { allow_alias: true }
+  value { name: "STATE_UNSPECIFIED" number: 0 }
+  value { name: "STARTED" number: 1 }
+  value { name: "RUNNING" number: 1 }
+}
+message_type {
+  name: "Task"
+  field { name: "kind" number: 1 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".test.enums.Task.Kind" json_name: "kind" }
+  field { name: "state" number: 2 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".test.enums.State" json_name: "state" }
+  field { name: "color" number: 3 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".test.enums.Palette.Color" json_name: "color" }
+  field { name: "severity" number: 4 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".test.deps.Severity" json_name: "severity" }
+  field { name: "level" number: 5 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".test.deps.Log.Level" json_name: "level" }
+  field { name: "kinds" number: 6 label: LABEL_REPEATED type: TYPE_ENUM type_name: ".test.enums.Task.Kind" json_name: "kinds" }
+  field { name: "levels" number: 7 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".test.enums.Task.LevelsEntry" json_name: "levels" }
+  field { name: "nothing" number: 8 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".google.protobuf.NullValue" json_name: "nothing" }
+  enum_type { name: "Kind" value { name: "KIND_UNSPECIFIED" number: 0 } value { name: "BATCH" number: 1 } }
+  nested_type {
+    name: "LevelsEntry" options { map_entry: true }
+    field { name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "key" }
+    field { name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".test.deps.Log.Level" json_name: "value" }
+  }
+}
+message_type {
+  name: "Palette"
+  enum_type { name: "Color" value { name: "COLOR_UNSPECIFIED" number: 0 } value { name: "RED" number: 1 } }
+}`)
+	structFile := protodesc.ToFileDescriptorProto(structpb.File_google_protobuf_struct_proto)
+	schema := generate(t, "", dep, structFile, file)["enums.jsonschema"]
+	defs := definitions(t, schema)
+
+	tests := []struct {
+		location string
+		property string
+		wantRef  string
+		want     []interface{}
+	}{
+		{location: "nested in the message", property: "kind", wantRef: "test.enums.Task.Kind", want: []interface{}{"KIND_UNSPECIFIED", 0.0, "BATCH", 1.0}},
+		{location: "top-level", property: "state", wantRef: "test.enums.State", want: []interface{}{"STATE_UNSPECIFIED", 0.0, "STARTED", 1.0, "RUNNING"}},
+		{location: "nested in a sibling message", property: "color", wantRef: "test.enums.Palette.Color", want: []interface{}{"COLOR_UNSPECIFIED", 0.0, "RED", 1.0}},
+		{location: "imported top-level", property: "severity", wantRef: "test.deps.Severity", want: []interface{}{"SEVERITY_UNSPECIFIED", 0.0, "HIGH", 1.0}},
+		{location: "imported nested", property: "level", wantRef: "test.deps.Log.Level", want: []interface{}{"LEVEL_UNSPECIFIED", 0.0, "DEBUG", 1.0}},
+		{location: "repeated", property: "kinds", wantRef: "test.enums.Task.Kind", want: []interface{}{"KIND_UNSPECIFIED", 0.0, "BATCH", 1.0}},
+		{location: "map value", property: "levels", wantRef: "test.deps.Log.Level", want: []interface{}{"LEVEL_UNSPECIFIED", 0.0, "DEBUG", 1.0}},
+	}
+	properties := defs["test.enums.Task"]["properties"].(map[string]interface{})
+	for _, tt := range tests {
+		property := properties[tt.property].(map[string]interface{})
+		for _, keyword := range []string{"items", "additionalProperties"} {
+			if elem, ok := property[keyword].(map[string]interface{}); ok {
+				property = elem
+			}
+		}
+		if got, want := property["$ref"], "#/definitions/"+tt.wantRef; got != want {
+			t.Errorf("%s: $ref = %v, want %s", tt.location, got, want)
+			continue
+		}
+		got, _ := json.Marshal(defs[tt.wantRef]["enum"])
+		want, _ := json.Marshal(tt.want)
+		if string(got) != string(want) {
+			t.Errorf("%s: enum = %s, want %s", tt.location, got, want)
+		}
+	}
+
+	if got := properties["nothing"].(map[string]interface{})["type"]; got != "null" {
+		t.Errorf("google.protobuf.NullValue: type = %v, want null", got)
+	}
+
+	valid := `{"kind": "BATCH", "state": "RUNNING", "color": 1, "severity": "HIGH", "level": 0, "kinds": ["BATCH", 0], "levels": {"a": "DEBUG"}, "nothing": null}`
+	if errs := validate(t, schema, "test.enums.Task", valid); len(errs) > 0 {
+		t.Errorf("%s is not valid: %v", valid, errs)
+	}
+	for _, invalid := range []string{`{"kind": "RED"}`, `{"severity": 2}`, `{"levels": {"a": "HIGH"}}`, `{"kinds": ["DEBUG"]}`} {
+		if errs := validate(t, schema, "test.enums.Task", invalid); len(errs) == 0 {
+			t.Errorf("%s must not be valid", invalid)
+		}
+	}
+}
+
+func TestGenDrafts(t *testing.T) {
+	file := fileDescriptorProto(t, `
+name: "drafts.proto" package: "test.drafts" syntax: "proto3"
+options { go_package: "example.com/test/drafts" }
+message_type {
+  name: "Pet"
+  field { name: "dog" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".test.drafts.Dog" json_name: "dog" oneof_index: 0 }
+  field { name: "cat" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "cat" oneof_index: 0 }
+  oneof_decl { name: "kind" }
+}
+message_type {
+  name: "Dog"
+  field { name: "name" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "name" }
+}`)
+
+	tests := []struct {
+		draft           string
+		wantSchema      string
+		wantDefinitions string // keyword of the definitions
+		wantDependency  string // keyword of the lenient oneof constraints
+		wantClosedBy    string // keyword which disallows the additional properties
+	}{
+		{draft: "04", wantSchema: "http://json-schema.org/draft-04/schema#", wantDefinitions: "definitions", wantDependency: "dependencies", wantClosedBy: "additionalProperties"},
+		{draft: "06", wantSchema: "http://json-schema.org/draft-06/schema#", wantDefinitions: "definitions", wantDependency: "dependencies", wantClosedBy: "additionalProperties"},
+		{draft: "07", wantSchema: "http://json-schema.org/draft-07/schema#", wantDefinitions: "definitions", wantDependency: "dependencies", wantClosedBy: "additionalProperties"},
+		{draft: "2019-09", wantSchema: "https://json-schema.org/draft/2019-09/schema", wantDefinitions: "$defs", wantDependency: "dependentSchemas", wantClosedBy: "unevaluatedProperties"},
+		{draft: "2020-12", wantSchema: "https://json-schema.org/draft/2020-12/schema", wantDefinitions: "$defs", wantDependency: "dependentSchemas", wantClosedBy: "unevaluatedProperties"},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.draft, func(t *testing.T) {
+			schema := generate(t, "draft="+tt.draft+",oneof_encoding=lenient,disallow_additional_properties", file)["drafts.jsonschema"]
+
+			var root map[string]interface{}
+			if err := json.Unmarshal([]byte(schema), &root); err != nil {
+				t.Fatalf("json.Unmarshal: %v", err)
+			}
+			if root["$schema"] != tt.wantSchema {
+				t.Errorf("$schema = %v, want %s", root["$schema"], tt.wantSchema)
+			}
+			defs, _ := root[tt.wantDefinitions].(map[string]interface{})
+			pet, _ := defs["test.drafts.Pet"].(map[string]interface{})
+			if pet == nil {
+				t.Fatalf("%s has no test.drafts.Pet: %s", tt.wantDefinitions, schema)
+			}
+
+			if _, ok := pet[tt.wantDependency]; !ok {
+				t.Errorf("Pet has no %s: %v", tt.wantDependency, pet)
+			}
+			if closed, ok := pet[tt.wantClosedBy]; closed != false {
+				t.Errorf("Pet.%s = %v (%t), want false", tt.wantClosedBy, closed, ok)
+			}
+			dog, _ := pet["properties"].(map[string]interface{})["dog"].(map[string]interface{})
+			if want := "#/" + tt.wantDefinitions + "/test.drafts.Dog"; dog["$ref"] != want {
+				t.Errorf("Pet.dog = %v, want {\"$ref\": %q}", dog, want)
+			}
+			if len(dog) != 1 {
+				t.Errorf("Pet.dog = %v, want no siblings of $ref", dog)
+			}
+		})
+	}
+}
+
+func TestGenOpenAPI(t *testing.T) {
+	owner := fileDescriptorProto(t, `
+name: "pets/v1/owner.proto" package: "pets.v1" syntax: "proto3"
+options { go_package: "example.com/test/pets" }
+message_type {
+  name: "Owner"
+  field { name: "name" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "name" }
+}`)
+	pet := fileDescriptorProto(t, `
+name: "pets/v1/pet.proto" package: "pets.v1" syntax: "proto3"
+dependency: "pets/v1/owner.proto"
+options { go_package: "example.com/test/pets" }
+message_type {
+  name: "Pet"
+  field { name: "name" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "name" }
+  field { name: "kind" number: 2 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".pets.v1.Kind" json_name: "kind" }
+  field { name: "owner" number: 3 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".pets.v1.Owner" json_name: "owner" }
+}
+enum_type { name: "Kind" value { name: "KIND_UNSPECIFIED" number: 0 } }`)
+
+	tests := []struct {
+		format      string
+		wantVersion string
+		want        map[string]string // property of pets.v1.Pet => its schema
+	}{
+		{
+			format:      outputFormatOpenAPI3,
+			wantVersion: "3.0.3",
+			want: map[string]string{
+				"name":  `{"type": "string", "nullable": true}`,
+				"kind":  `{"allOf": [{"$ref": "#/components/schemas/pets.v1.Kind"}], "nullable": true}`,
+				"owner": `{"$ref": "#/components/schemas/pets.v1.Owner"}`,
+			},
+		},
+		{
+			format:      outputFormatOpenAPI31,
+			wantVersion: "3.1.0",
+			want: map[string]string{
+				"name":  `{"type": ["string", "null"]}`,
+				"kind":  `{"oneOf": [{"type": "null"}, {"$ref": "#/components/schemas/pets.v1.Kind"}]}`,
+				"owner": `{"$ref": "#/components/schemas/pets.v1.Owner"}`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.format, func(t *testing.T) {
+			out := generateFiles(t, "allow_null_values,output_format="+tt.format, []string{"pets/v1/owner.proto", "pets/v1/pet.proto"}, owner, pet)
+			if len(out) != 1 {
+				t.Fatalf("generated %d files, want a document of the package", len(out))
+			}
+			doc, ok := out["pets/v1/pets.v1.openapi.json"]
+			if !ok {
+				t.Fatalf("generated %v, want pets/v1/pets.v1.openapi.json", out)
+			}
+
+			var root struct {
+				OpenAPI    string `json:"openapi"`
+				Components struct {
+					Schemas map[string]struct {
+						Properties map[string]interface{} `json:"properties"`
+					} `json:"schemas"`
+				} `json:"components"`
+			}
+			if err := json.Unmarshal([]byte(doc), &root); err != nil {
+				t.Fatalf("json.Unmarshal: %v", err)
+			}
+			if root.OpenAPI != tt.wantVersion {
+				t.Errorf("openapi = %q, want %q", root.OpenAPI, tt.wantVersion)
+			}
+			for _, name := range []string{"pets.v1.Kind", "pets.v1.Owner", "pets.v1.Pet"} {
+				if _, ok := root.Components.Schemas[name]; !ok {
+					t.Errorf("components.schemas has no %s", name)
+				}
+			}
+			for property, want := range tt.want {
+				var wantSchema interface{}
+				if err := json.Unmarshal([]byte(want), &wantSchema); err != nil {
+					t.Fatalf("json.Unmarshal: %v", err)
+				}
+				if got := root.Components.Schemas["pets.v1.Pet"].Properties[property]; !reflect.DeepEqual(got, wantSchema) {
+					t.Errorf("Pet.%s = %v, want %v", property, got, wantSchema)
+				}
+			}
+			if tt.format == outputFormatOpenAPI3 && strings.Contains(doc, `"type": "null"`) {
+				t.Errorf("OpenAPI 3.0 document has the null type: %s", doc)
+			}
+		})
+	}
+}
+
+func TestGenEncodings(t *testing.T) {
+	file := fileDescriptorProto(t, `
+name: "encodings.proto" package: "test.encodings" syntax: "proto3"
+options { go_package: "example.com/test/encodings" }
+message_type {
+  name: "Flags"
+  field { name: "flags" number: 1 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".test.encodings.Flags.FlagsEntry" json_name: "flags" }
+  field { name: "version" number: 2 label: LABEL_OPTIONAL type: TYPE_UINT64 json_name: "version" }
+  nested_type {
+    name: "FlagsEntry" options { map_entry: true }
+    field { name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_BOOL json_name: "key" }
+    field { name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_BOOL json_name: "value" }
+  }
+}`)
+
+	var want interface{}
+	if err := json.Unmarshal([]byte(generate(t, "", file)["encodings.jsonschema"]), &want); err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	}
+
+	tests := []struct {
+		parameter  string
+		fileName   string
+		wantIndent string // indentation of the second line
+		unmarshal  func([]byte, interface{}) error
+	}{
+		{parameter: "encoding=json", fileName: "encodings.jsonschema", wantIndent: "    ", unmarshal: json.Unmarshal},
+		{parameter: "encoding=json,indent=2", fileName: "encodings.jsonschema", wantIndent: "  ", unmarshal: json.Unmarshal},
+		{parameter: "encoding=json,indent=0", fileName: "encodings.jsonschema", wantIndent: "", unmarshal: json.Unmarshal},
+		{parameter: "encoding=yaml", fileName: "encodings.jsonschema.yaml", wantIndent: "", unmarshal: yaml.Unmarshal},
+		{parameter: "encoding=yaml,indent=4", fileName: "encodings.jsonschema.yaml", wantIndent: "", unmarshal: yaml.Unmarshal},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.parameter, func(t *testing.T) {
+			schema, ok := generate(t, tt.parameter, file)[tt.fileName]
+			if !ok {
+				t.Fatalf("%s is not generated", tt.fileName)
+			}
+			if again := generate(t, tt.parameter, file)[tt.fileName]; again != schema {
+				t.Errorf("the regenerated file differs:\n%s\n---\n%s", schema, again)
+			}
+			if !strings.HasSuffix(schema, "\n") {
+				t.Errorf("the file does not end with a newline")
+			}
+
+			// the first member is "$schema"
+			lines := strings.Split(schema, "\n")
+			if first := strings.TrimSpace(lines[0]); !strings.HasPrefix(first, `{"$schema"`) && first != "{" && !strings.HasPrefix(first, "$schema:") {
+				t.Errorf("the first line = %q, want $schema", lines[0])
+			}
+			if len(lines) > 1 && tt.wantIndent != "" && !strings.HasPrefix(lines[1], tt.wantIndent+`"$schema"`) {
+				t.Errorf("the second line = %q, want the indentation %q", lines[1], tt.wantIndent)
+			}
+
+			var got interface{}
+			if err := tt.unmarshal([]byte(schema), &got); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if !reflect.DeepEqual(normalize(got), normalize(want)) {
+				t.Errorf("the decoded schema differs from the JSON one:\n%s", schema)
+			}
+		})
+	}
+
+	// the indentations of YAML narrower than 2 are replaced by the default one with a warning
+	for _, parameter := range []string{"encoding=yaml,indent=0", "indent=1,encoding=yaml"} {
+		parameter := parameter
+		t.Run(parameter, func(t *testing.T) {
+			logs := observeWarnings(t)
+			got := generate(t, parameter, file)["encodings.jsonschema.yaml"]
+			if want := generate(t, "encoding=yaml", file)["encodings.jsonschema.yaml"]; got != want {
+				t.Errorf("the file differs from the one of the default indentation:\n%s\n---\n%s", got, want)
+			}
+			if logs.Len() == 0 {
+				t.Errorf("no warning of the indentation")
+			}
+		})
+	}
+}
+
+// normalize converts the numbers decoded from YAML into float64 as encoding/json decodes them.
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = normalize(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = normalize(value)
+		}
+	case int:
+		return float64(v)
+	}
+	return v
+}
+
+func TestGenPropertyOrder(t *testing.T) {
+	file := fileDescriptorProto(t, `
+name: "order.proto" package: "test.order" syntax: "proto3"
+options { go_package: "example.com/test/order" }
+message_type {
+  name: "Fruits"
+  field { name: "zebra_fruit" number: 3 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "zebraFruit" }
+  field { name: "apple" number: 1 label: LABEL_OPTIONAL type: TYPE_INT32 json_name: "apple" }
+  field { name: "mango" number: 2 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".test.order.Fruits.Mango" json_name: "mango" }
+  nested_type {
+    name: "Mango"
+    field { name: "ripe" number: 2 label: LABEL_OPTIONAL type: TYPE_BOOL json_name: "ripe" }
+    field { name: "origin" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "origin" }
+  }
+}`)
+
+	for _, order := range []PropertyOrder{PropertyOrderDeclaration, PropertyOrderAlphabetical} {
+		order := order
+		t.Run(string(order), func(t *testing.T) {
+			parameter := "property_naming=both,property_order=" + string(order)
+			schema := generate(t, parameter, file)["order.jsonschema"]
+			if again := generate(t, parameter, file)["order.jsonschema"]; again != schema {
+				t.Fatalf("the regenerated schema differs:\n%s\n---\n%s", schema, again)
+			}
+
+			golden := path.Join("testdata", "property_order", string(order)+".jsonschema")
+			if *update {
+				if err := os.WriteFile(golden, []byte(schema), 0o644); err != nil {
+					t.Fatalf("os.WriteFile: %v", err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("os.ReadFile: %v", err)
+			}
+			if schema != string(want) {
+				t.Errorf("the schema differs from %s:\n%s", golden, schema)
+			}
+		})
+	}
+}
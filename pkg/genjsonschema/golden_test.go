@@ -0,0 +1,148 @@
+// Copyright 2019 The protoc-gen-jsonschema Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genjsonschema
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// goldenDir is the directory of the fixture .proto files, and the golden files in its subdirectory of each case.
+const goldenDir = "testdata/golden"
+
+// goldenCases is the parameters the fixtures are generated with, keyed by the names of the subdirectories.
+var goldenCases = []struct {
+	name      string
+	parameter string
+}{
+	{name: "default"},
+	{name: "allow_null_values", parameter: "allow_null_values"},
+	{name: "strict", parameter: "disallow_additional_properties,disallow_bigints_as_strings"},
+}
+
+// compileProtos compiles the .proto files in the directory, and returns the FileDescriptorProtos of the files and
+// their dependencies, in the topological order as protoc puts them into a CodeGeneratorRequest.
+func compileProtos(t *testing.T, dir string, names ...string) []*descriptorpb.FileDescriptorProto {
+	t.Helper()
+
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			ImportPaths: []string{dir},
+		}),
+		SourceInfoMode: protocompile.SourceInfoStandard,
+	}
+	files, err := compiler.Compile(context.Background(), names...)
+	if err != nil {
+		t.Fatalf("compile %v: %v", names, err)
+	}
+
+	fds := make([]protoreflect.FileDescriptor, len(files))
+	for i, fd := range files {
+		fds[i] = fd
+	}
+
+	return linkedFiles(fds...)
+}
+
+// linkedFiles returns the FileDescriptorProtos of the files and their imports, in the topological order as protoc puts
+// them into a CodeGeneratorRequest.
+func linkedFiles(fds ...protoreflect.FileDescriptor) []*descriptorpb.FileDescriptorProto {
+	var files []*descriptorpb.FileDescriptorProto
+	seen := make(map[string]bool)
+	var add func(fd protoreflect.FileDescriptor)
+	add = func(fd protoreflect.FileDescriptor) {
+		if seen[fd.Path()] {
+			return
+		}
+		seen[fd.Path()] = true
+		imports := fd.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			add(imports.Get(i).FileDescriptor)
+		}
+		files = append(files, protodesc.ToFileDescriptorProto(fd))
+	}
+	for _, fd := range fds {
+		add(fd)
+	}
+
+	return files
+}
+
+// diffLines returns the first different line of got and want, or empty if they are the same.
+func diffLines(got, want string) string {
+	gotLines, wantLines := strings.Split(got, "\n"), strings.Split(want, "\n")
+	for i := 0; i < len(gotLines) || i < len(wantLines); i++ {
+		var g, w string
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if g != w {
+			return fmt.Sprintf("line %d:\n-%s\n+%s", i+1, w, g)
+		}
+	}
+	return ""
+}
+
+func TestGolden(t *testing.T) {
+	protos, err := filepath.Glob(filepath.Join(goldenDir, "*.proto"))
+	if err != nil {
+		t.Fatalf("filepath.Glob: %v", err)
+	}
+	if len(protos) == 0 {
+		t.Fatalf("no fixtures in %s", goldenDir)
+	}
+
+	for _, tc := range goldenCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			for _, proto := range protos {
+				name := filepath.Base(proto)
+				t.Run(name, func(t *testing.T) {
+					out := generate(t, tc.parameter, compileProtos(t, goldenDir, name)...)
+
+					jsonSchemaFileName := strings.TrimSuffix(name, ".proto") + ".jsonschema"
+					got, ok := out[jsonSchemaFileName]
+					if !ok {
+						t.Fatalf("%s is not generated: %v", jsonSchemaFileName, out)
+					}
+
+					golden := filepath.Join(goldenDir, tc.name, jsonSchemaFileName)
+					if *update {
+						if err := os.MkdirAll(filepath.Dir(golden), 0o755); err != nil {
+							t.Fatalf("os.MkdirAll: %v", err)
+						}
+						if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+							t.Fatalf("os.WriteFile: %v", err)
+						}
+						return
+					}
+
+					want, err := os.ReadFile(golden)
+					if err != nil {
+						t.Fatalf("os.ReadFile: %v (run go test with -update to create it)", err)
+					}
+					if diff := diffLines(got, string(want)); diff != "" {
+						t.Errorf("%s differs from the golden file (run go test with -update to accept it):\n%s", golden, diff)
+					}
+				})
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright 2019 The protoc-gen-jsonschema Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genjsonschema
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/xeipuuv/gojsonschema"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// list of the versions of the OpenAPI documents.
+const (
+	openAPIVersion30 = "3.0.3"
+	openAPIVersion31 = "3.1.0"
+)
+
+// renderOpenAPI converts the package of the file into the OpenAPI document, which holds the schemas of all the
+// messages and enums of the package in its components.
+//
+// The files of a package are converted together when it is called for the last of them, so that each package is
+// written once. It returns nil output for the other files.
+func renderOpenAPI(gen *protogen.Plugin, file *protogen.File, opts *options) (*output, error) {
+	var files []*protogen.File
+	for _, f := range gen.Files {
+		if f.Generate && f.Desc.Package() == file.Desc.Package() {
+			files = append(files, f)
+		}
+	}
+	if len(files) == 0 || files[len(files)-1] != file {
+		return nil, nil
+	}
+
+	fds := make([]protoreflect.FileDescriptor, len(files))
+	for i, file := range files {
+		fds[i] = file.Desc
+	}
+	registry, err := opts.files(fds...)
+	if err != nil {
+		return nil, err
+	}
+
+	c := newConverter(opts.Options, registry)
+	for _, fd := range fds {
+		log.Debugf("converting file (%v)", fd.Path())
+		c.defineFile(fd)
+	}
+	if err := c.err(); err != nil {
+		return nil, err
+	}
+
+	title := string(file.Desc.Package())
+	fileName := path.Join(path.Dir(files[0].Desc.Path()), title+opts.extension(".openapi.json"))
+	if title == "" {
+		title = files[0].Desc.Path()
+		fileName = path.Join(path.Dir(files[0].Desc.Path()), opts.extension("openapi.json"))
+	}
+
+	e := newEmitter(opts)
+	openAPIJSON, err := opts.marshal(e.document(title, c.definitions))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode the OpenAPI document of %s: %w", title, err)
+	}
+
+	return &output{
+		fileName:     fileName,
+		goImportPath: files[0].GoImportPath,
+		content:      openAPIJSON,
+	}, nil
+}
+
+// document returns the JSON representation of the OpenAPI document which holds the definitions as the schemas of its
+// components.
+func (e *emitter) document(title string, definitions Definitions) interface{} {
+	info := &object{}
+	info.set("title", title)
+	// the documents describe no operations, so they have no versions of their own
+	info.set("version", "0.0.0")
+
+	components := &object{}
+	components.set("schemas", e.schemaMap(definitions))
+
+	o := &object{}
+	o.set("openapi", e.openapi)
+	o.set("info", info)
+	if e.openAPI30() {
+		// "paths" is required until 3.1
+		o.set("paths", &object{})
+	}
+	o.set("components", components)
+
+	return o
+}
+
+// rewriteNull rewrites the schema o converted from t, which accepts null by the null type, into the representation of
+// OpenAPI.
+//
+// OpenAPI 3.0 has no null type but "nullable", and OpenAPI 3.1 describes the nullable types better by the type arrays
+// such as ["string", "null"] than by "oneOf".
+func (e *emitter) rewriteNull(o *object, t *Type) {
+	if o.get("type") == gojsonschema.TYPE_NULL {
+		if e.openAPI30() {
+			o.delete("type")
+			o.set("enum", []interface{}{nil})
+			o.set("nullable", true)
+		}
+		return
+	}
+
+	oneOf, _ := o.get("oneOf").([]interface{})
+	if len(oneOf) != len(t.OneOf) {
+		return
+	}
+	var others []interface{}
+	for i, s := range t.OneOf {
+		if !isNullSchema(s) {
+			others = append(others, oneOf[i])
+		}
+	}
+	if len(others) == len(oneOf) || len(others) == 0 {
+		return
+	}
+
+	if e.openAPI30() {
+		o.delete("oneOf")
+		switch other, _ := others[0].(*object); {
+		case len(others) > 1:
+			o.set("oneOf", others)
+		case typeOnly(other) && o.get("type") == nil:
+			o.set("type", other.get("type"))
+		case other != nil && other.get("$ref") == nil && annotationsOnly(o):
+			merge(o, other)
+		default:
+			// the siblings of "$ref" are ignored in OpenAPI 3.0
+			allOf, _ := o.get("allOf").([]interface{})
+			o.set("allOf", append(allOf, others[0]))
+		}
+		o.set("nullable", true)
+		return
+	}
+
+	types := make([]interface{}, 0, len(others)+1)
+	for _, other := range others {
+		other, _ := other.(*object)
+		if !typeOnly(other) {
+			types = nil
+			break
+		}
+		types = append(types, other.get("type"))
+	}
+	switch other, _ := others[0].(*object); {
+	case types != nil && o.get("type") == nil:
+		o.delete("oneOf")
+		o.set("type", append(types, gojsonschema.TYPE_NULL))
+	case len(others) == 1 && other != nil && other.get("$ref") == nil && annotationsOnly(o):
+		if typ, ok := other.get("type").(string); ok {
+			o.delete("oneOf")
+			merge(o, other)
+			o.set("type", []interface{}{typ, gojsonschema.TYPE_NULL})
+		}
+	}
+}
+
+// isNullSchema reports whether the schema accepts only null.
+func isNullSchema(t *Type) bool {
+	o, ok := (&emitter{}).schema(t).(*object)
+	return ok && typeOnly(o) && o.get("type") == gojsonschema.TYPE_NULL
+}
+
+// typeOnly reports whether the schema has only the "type" keyword of a single type.
+func typeOnly(o *object) bool {
+	if o == nil || len(o.keys) != 1 {
+		return false
+	}
+	_, ok := o.get("type").(string)
+	return ok
+}
+
+// annotationsOnly reports whether the schema has no keywords but the annotations and "oneOf" being rewritten.
+func annotationsOnly(o *object) bool {
+	for _, key := range o.keys {
+		if key != "title" && key != "description" && key != "oneOf" {
+			return false
+		}
+	}
+	return true
+}
+
+// merge sets the members of src to dst, keeping the members dst already has.
+func merge(dst, src *object) {
+	for _, key := range src.keys {
+		if dst.get(key) == nil {
+			dst.set(key, src.get(key))
+		}
+	}
+}
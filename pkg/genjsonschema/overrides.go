@@ -0,0 +1,200 @@
+// Copyright 2019 The protoc-gen-jsonschema Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genjsonschema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/zchee/protoc-gen-jsonschema/jsonschema"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// hidden reports whether the jsonschema option of the file, message, field, enum or enum value hides it.
+func hidden(desc protoreflect.Descriptor) bool {
+	switch desc := desc.(type) {
+	case protoreflect.FileDescriptor:
+		opts, _ := getExtension(desc, jsonschema.E_File).(*jsonschema.FileOptions)
+		return opts.GetHide()
+	case protoreflect.MessageDescriptor:
+		opts, _ := getExtension(desc, jsonschema.E_Message).(*jsonschema.MessageOptions)
+		return opts.GetHide()
+	case protoreflect.FieldDescriptor:
+		opts, _ := getExtension(desc, jsonschema.E_Field).(*jsonschema.FieldOptions)
+		return opts.GetHide()
+	case protoreflect.EnumDescriptor:
+		opts, _ := getExtension(desc, jsonschema.E_Enum).(*jsonschema.EnumOptions)
+		return opts.GetHide()
+	case protoreflect.EnumValueDescriptor:
+		opts, _ := getExtension(desc, jsonschema.E_EnumValue).(*jsonschema.EnumValueOptions)
+		return opts.GetHide()
+	}
+
+	return false
+}
+
+// fileTitle returns the title of the document of the file given by the "jsonschema.file" option.
+func fileTitle(fd protoreflect.FileDescriptor) string {
+	opts, _ := getExtension(fd, jsonschema.E_File).(*jsonschema.FileOptions)
+	return opts.GetTitle()
+}
+
+// additionalPropertiesDisallowed reports whether the schema of the message forbids the properties other than the
+// fields, by the "jsonschema.message" option, the "jsonschema.file" option of its file, or the
+// disallow_additional_properties parameter in this order.
+func (c *converter) additionalPropertiesDisallowed(msg protoreflect.MessageDescriptor) bool {
+	if opts, ok := getExtension(msg, jsonschema.E_Message).(*jsonschema.MessageOptions); ok && opts.AdditionalProperties != nil {
+		return !opts.GetAdditionalProperties()
+	}
+	if opts, ok := getExtension(msg.ParentFile(), jsonschema.E_File).(*jsonschema.FileOptions); ok && opts.AdditionalProperties != nil {
+		return !opts.GetAdditionalProperties()
+	}
+
+	return c.opts.DisallowAdditionalProperties
+}
+
+// applyFieldOptions applies the "jsonschema.field" option of the field to the schema t of the field, and requires the
+// field in the message schema msgType if the option does.
+//
+// It is called after the schema is converted from the type and the validation rules of the field, so the option
+// overrides them.
+func (c *converter) applyFieldOptions(msgType, t *Type, field protoreflect.FieldDescriptor) error {
+	opts, ok := getExtension(field, jsonschema.E_Field).(*jsonschema.FieldOptions)
+	if !ok {
+		return nil
+	}
+
+	if opts.GetRequired() {
+		c.require(msgType, field)
+	}
+
+	if opts.GetSchema() != "" {
+		schema, err := literalSchema(opts.GetSchema())
+		if err != nil {
+			return err
+		}
+		*t = *schema
+		return nil
+	}
+
+	if opts.GetTitle() != "" {
+		t.Title = opts.GetTitle()
+	}
+	if opts.GetFormat() != "" || opts.GetDefault() != "" || opts.GetConst() != "" {
+		// the keywords constrain the enum and message fields only out of their references
+		c.unref(t)
+	}
+	if opts.GetFormat() != "" {
+		t.Format = opts.GetFormat()
+	}
+	if opts.GetDefault() != "" {
+		v, err := parseJSON("default", opts.GetDefault())
+		if err != nil {
+			return err
+		}
+		t.Default = v
+	}
+	if opts.GetConst() != "" {
+		v, err := parseJSON("const", opts.GetConst())
+		if err != nil {
+			return err
+		}
+		t.Const = v
+	}
+
+	return setExamples(t, opts.GetExamples())
+}
+
+// applyMessageOptions applies the "jsonschema.message" option of the message to the message schema t.
+func (c *converter) applyMessageOptions(t *Type, msg protoreflect.MessageDescriptor) error {
+	opts, ok := getExtension(msg, jsonschema.E_Message).(*jsonschema.MessageOptions)
+	if !ok {
+		return nil
+	}
+
+	if opts.GetSchema() != "" {
+		schema, err := literalSchema(opts.GetSchema())
+		if err != nil {
+			return err
+		}
+		*t = *schema
+		return nil
+	}
+
+	if opts.GetTitle() != "" {
+		t.Title = opts.GetTitle()
+	}
+
+	return setExamples(t, opts.GetExamples())
+}
+
+// applyEnumOptions applies the "jsonschema.enum" option of the enum to the enum schema t.
+func (c *converter) applyEnumOptions(t *Type, enum protoreflect.EnumDescriptor) {
+	opts, ok := getExtension(enum, jsonschema.E_Enum).(*jsonschema.EnumOptions)
+	if !ok {
+		return
+	}
+
+	if opts.GetTitle() != "" {
+		t.Title = opts.GetTitle()
+	}
+}
+
+// setExamples sets the examples of the schema t from their JSON texts, if any.
+func setExamples(t *Type, texts []string) error {
+	if len(texts) == 0 {
+		return nil
+	}
+
+	examples := make([]interface{}, len(texts))
+	for i, text := range texts {
+		v, err := parseJSON("examples", text)
+		if err != nil {
+			return err
+		}
+		examples[i] = v
+	}
+	t.Examples = examples
+
+	return nil
+}
+
+// parseJSON decodes the JSON text of the option named name, keeping the numbers as they are written.
+func parseJSON(name, text string) (interface{}, error) {
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("invalid JSON of %s %q: %w", name, text, err)
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid JSON of %s %q: trailing data", name, text)
+	}
+
+	return v, nil
+}
+
+// literalSchema returns the schema of the JSON text, which must be an object or a boolean.
+//
+// The boolean schemas are kept as such rather than literally, so that they are rewritten for draft-04 and OpenAPI 3.0,
+// which have none.
+func literalSchema(text string) (*Type, error) {
+	v, err := parseJSON("schema", text)
+	if err != nil {
+		return nil, err
+	}
+	switch v := v.(type) {
+	case bool:
+		return boolSchema(v), nil
+	case map[string]interface{}:
+		return &Type{Raw: json.RawMessage(strings.TrimSpace(text))}, nil
+	}
+
+	return nil, fmt.Errorf("invalid schema %q: not an object or a boolean", text)
+}
@@ -0,0 +1,241 @@
+// Copyright 2019 The protoc-gen-jsonschema Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genjsonschema
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zchee/protoc-gen-jsonschema/jsonschema"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestGenJSONSchemaOptions(t *testing.T) {
+	file := fileDescriptorProto(t, `
+name: "profiles.proto" package: "test.profiles" syntax: "proto3"
+dependency: "jsonschema/options.proto"
+options { go_package: "example.com/test/profiles" [jsonschema.file] { title: "Profiles" additional_properties: false } }
+enum_type {
+  name: "Level"
+  options { [jsonschema.enum] { title: "The level" } }
+  value { name: "LEVEL_UNSPECIFIED" number: 0 }
+  value { name: "LEVEL_INTERNAL" number: 1 options { [jsonschema.enum_value] { hide: true } } }
+  value { name: "LEVEL_PUBLIC" number: 2 }
+}
+enum_type { name: "Secret" options { [jsonschema.enum] { hide: true } } value { name: "SECRET_UNSPECIFIED" number: 0 } }
+message_type {
+  name: "Profile"
+  options { [jsonschema.message] { title: "A profile" examples: '{"name": "bob"}' } }
+  field { name: "name" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "name" options { [jsonschema.field] { title: "Name" examples: ['"bob"', '"alice"'] format: "hostname" default: '"anonymous"' required: true } } }
+  field { name: "kind" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "kind" options { [jsonschema.field] { const: '"user"' } } }
+  field { name: "password" number: 3 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "password" options { [jsonschema.field] { hide: true } } }
+  field { name: "age" number: 4 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "age" options { [jsonschema.field] { schema: '{"type": "integer", "minimum": 1}' } } }
+  field { name: "level" number: 5 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".test.profiles.Level" json_name: "level" }
+  field { name: "phone" number: 6 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "phone" oneof_index: 0 }
+  field { name: "fax" number: 7 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "fax" oneof_index: 0 options { [jsonschema.field] { hide: true } } }
+  oneof_decl { name: "contact" }
+}
+message_type { name: "Loose" options { [jsonschema.message] { additional_properties: true } } }
+message_type { name: "Internal" options { [jsonschema.message] { hide: true } } }
+message_type { name: "Raw" options { [jsonschema.message] { schema: '{"type": "string"}' } } }`)
+	files := append(linkedFiles(jsonschema.File_jsonschema_options_proto), file)
+
+	schema := generate(t, "draft=07", files...)["profiles.jsonschema"]
+
+	t.Run("root", func(t *testing.T) {
+		var root struct {
+			Title string                   `json:"title"`
+			AnyOf []map[string]interface{} `json:"anyOf"`
+		}
+		if err := json.Unmarshal([]byte(schema), &root); err != nil {
+			t.Fatalf("json.Unmarshal: %v", err)
+		}
+		if root.Title != "Profiles" {
+			t.Errorf("title = %q, want %q", root.Title, "Profiles")
+		}
+		var refs []string
+		for _, s := range root.AnyOf {
+			refs = append(refs, s["$ref"].(string))
+		}
+		want := []string{"#/definitions/test.profiles.Profile", "#/definitions/test.profiles.Loose", "#/definitions/test.profiles.Raw"}
+		if !reflect.DeepEqual(refs, want) {
+			t.Errorf("anyOf = %q, want %q", refs, want)
+		}
+	})
+
+	t.Run("definitions", func(t *testing.T) {
+		defs := definitions(t, schema)
+		for _, name := range []string{"test.profiles.Internal", "test.profiles.Secret"} {
+			if _, ok := defs[name]; ok {
+				t.Errorf("hidden %s is defined", name)
+			}
+		}
+
+		tests := []struct {
+			name   string
+			schema interface{}
+			want   string
+		}{
+			{name: "message title", schema: defs["test.profiles.Profile"]["title"], want: `"A profile"`},
+			{name: "message examples", schema: defs["test.profiles.Profile"]["examples"], want: `[{"name": "bob"}]`},
+			{name: "message schema", schema: defs["test.profiles.Raw"], want: `{"type": "string"}`},
+			{name: "field", schema: defs["test.profiles.Profile"]["properties"].(map[string]interface{})["name"], want: `{"title": "Name", "type": "string", "format": "hostname", "default": "anonymous", "examples": ["bob", "alice"]}`},
+			{name: "field schema", schema: defs["test.profiles.Profile"]["properties"].(map[string]interface{})["age"], want: `{"type": "integer", "minimum": 1}`},
+			{name: "enum title", schema: defs["test.profiles.Level"]["title"], want: `"The level"`},
+			{name: "enum values", schema: defs["test.profiles.Level"]["enum"], want: `["LEVEL_UNSPECIFIED", 0, "LEVEL_PUBLIC", 2]`},
+		}
+		for _, tt := range tests {
+			var want interface{}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatalf("json.Unmarshal: %v", err)
+			}
+			if !reflect.DeepEqual(tt.schema, want) {
+				t.Errorf("%s = %v, want %s", tt.name, tt.schema, tt.want)
+			}
+		}
+	})
+
+	t.Run("validate", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			document string
+			valid    bool
+		}{
+			{name: "test.profiles.Profile", document: `{"name": "bob", "kind": "user", "age": 20, "level": "LEVEL_PUBLIC", "phone": "1"}`, valid: true},
+			{name: "test.profiles.Profile", document: `{}`, valid: false},
+			{name: "test.profiles.Profile", document: `{"name": "bob", "kind": "admin"}`, valid: false},
+			{name: "test.profiles.Profile", document: `{"name": "bob", "age": "20"}`, valid: false},
+			{name: "test.profiles.Profile", document: `{"name": "bob", "level": "LEVEL_INTERNAL"}`, valid: false},
+			{name: "test.profiles.Profile", document: `{"name": "bob", "password": "x"}`, valid: false},
+			{name: "test.profiles.Profile", document: `{"name": "bob", "fax": "1"}`, valid: false},
+			{name: "test.profiles.Loose", document: `{"any": 1}`, valid: true},
+			{name: "test.profiles.Raw", document: `"x"`, valid: true},
+		}
+		for _, tt := range tests {
+			errs := validate(t, schema, tt.name, tt.document)
+			if valid := len(errs) == 0; valid != tt.valid {
+				t.Errorf("%s %s: valid = %t, want %t: %v", tt.name, tt.document, valid, tt.valid, errs)
+			}
+		}
+	})
+
+	t.Run("referenced field", func(t *testing.T) {
+		paints := fileDescriptorProto(t, `
+name: "paints.proto" package: "test.paints" syntax: "proto3"
+dependency: "jsonschema/options.proto"
+options { go_package: "example.com/test/paints" }
+enum_type {
+  name: "Color"
+  value { name: "COLOR_UNSPECIFIED" number: 0 }
+  value { name: "RED" number: 1 }
+  value { name: "BLUE" number: 2 }
+}
+message_type {
+  name: "Paint"
+  field { name: "color" number: 1 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".test.paints.Color" json_name: "color" options { [jsonschema.field] { const: '"RED"' } } }
+  field { name: "base" number: 2 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".test.paints.Color" json_name: "base" options { [jsonschema.field] { default: '"BLUE"' } } }
+}`)
+		files := append(linkedFiles(jsonschema.File_jsonschema_options_proto), paints)
+
+		// the siblings of "$ref" are ignored until 2019-09, so the reference is moved into "allOf"
+		for _, parameter := range []string{"", "draft=07"} {
+			schema := generate(t, parameter, files...)["paints.jsonschema"]
+			tests := []struct {
+				document string
+				valid    bool
+			}{
+				{document: `{"color": "RED", "base": "BLUE"}`, valid: true},
+				{document: `{"color": "BLUE"}`, valid: false},
+				{document: `{"color": "RED", "base": "GREEN"}`, valid: false},
+			}
+			for _, tt := range tests {
+				errs := validate(t, schema, "test.paints.Paint", tt.document)
+				if valid := len(errs) == 0; valid != tt.valid {
+					t.Errorf("%q: %s: valid = %t, want %t: %v", parameter, tt.document, valid, tt.valid, errs)
+				}
+			}
+		}
+	})
+
+	t.Run("hidden file", func(t *testing.T) {
+		hiddenFile := fileDescriptorProto(t, `
+name: "hidden.proto" package: "test.hidden" syntax: "proto3"
+dependency: "jsonschema/options.proto"
+options { go_package: "example.com/test/hidden" [jsonschema.file] { hide: true } }
+message_type { name: "Hidden" }`)
+		out := generate(t, "", append(linkedFiles(jsonschema.File_jsonschema_options_proto), hiddenFile)...)
+		if len(out) > 0 {
+			t.Errorf("generated %d files for the hidden file", len(out))
+		}
+	})
+
+	t.Run("boolean schema", func(t *testing.T) {
+		booleans := fileDescriptorProto(t, `
+name: "booleans.proto" package: "test.booleans" syntax: "proto3"
+dependency: "jsonschema/options.proto"
+options { go_package: "example.com/test/booleans" }
+message_type {
+  name: "Booleans"
+  field { name: "any" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "any" options { [jsonschema.field] { schema: "true" } } }
+  field { name: "none" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "none" options { [jsonschema.field] { schema: "false" } } }
+}`)
+		files := append(linkedFiles(jsonschema.File_jsonschema_options_proto), booleans)
+
+		tests := []struct {
+			parameter string
+			file      string
+			path      string
+			want      string
+		}{
+			{parameter: "draft=07", file: "booleans.jsonschema", path: "definitions", want: `{"any": true, "none": false}`},
+			// draft-04 and OpenAPI 3.0 have no boolean schemas
+			{parameter: "", file: "booleans.jsonschema", path: "definitions", want: `{"any": {}, "none": {"not": {}}}`},
+			{parameter: "output_format=openapi3", file: "test.booleans.openapi.json", path: "components", want: `{"any": {}, "none": {"not": {}}}`},
+		}
+		for _, tt := range tests {
+			var doc map[string]interface{}
+			if err := json.Unmarshal([]byte(generate(t, tt.parameter, files...)[tt.file]), &doc); err != nil {
+				t.Fatalf("%q: json.Unmarshal: %v", tt.parameter, err)
+			}
+			defs := doc[tt.path].(map[string]interface{})
+			if tt.path == "components" {
+				defs = defs["schemas"].(map[string]interface{})
+			}
+			var want interface{}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatalf("json.Unmarshal: %v", err)
+			}
+			if got := defs["test.booleans.Booleans"].(map[string]interface{})["properties"]; !reflect.DeepEqual(got, want) {
+				t.Errorf("%q: properties = %v, want %s", tt.parameter, got, tt.want)
+			}
+		}
+	})
+
+	t.Run("invalid JSON", func(t *testing.T) {
+		invalid := fileDescriptorProto(t, `
+name: "invalid.proto" package: "test.invalid" syntax: "proto3"
+dependency: "jsonschema/options.proto"
+message_type {
+  name: "Invalid"
+  field { name: "name" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "name" options { [jsonschema.field] { default: "anonymous" } } }
+}`)
+		registry, err := protodesc.NewFiles(&descriptorpb.FileDescriptorSet{File: append(linkedFiles(jsonschema.File_jsonschema_options_proto), invalid)})
+		if err != nil {
+			t.Fatalf("protodesc.NewFiles: %v", err)
+		}
+		fd, err := registry.FindFileByPath("invalid.proto")
+		if err != nil {
+			t.Fatalf("FindFileByPath: %v", err)
+		}
+
+		_, err = NewGenerator(Options{}).File(fd)
+		if err == nil || !strings.Contains(err.Error(), "test.invalid.Invalid.name") || !strings.Contains(err.Error(), "invalid JSON of default") {
+			t.Errorf("File() error = %v, want the invalid JSON of default of test.invalid.Invalid.name", err)
+		}
+	})
+}
@@ -0,0 +1,222 @@
+// Copyright 2019 The protoc-gen-jsonschema Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genjsonschema
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// output is the content of an output file, which is rendered concurrently and written to the plugin afterwards,
+// because protogen.Plugin is not safe for concurrent use.
+type output struct {
+	fileName     string
+	goImportPath protogen.GoImportPath
+	content      []byte
+}
+
+// write adds the output file to the response of gen. It does nothing for nil output.
+func (out *output) write(gen *protogen.Plugin) error {
+	if out == nil {
+		return nil
+	}
+
+	g := gen.NewGeneratedFile(out.fileName, out.goImportPath)
+	if _, err := g.Write(out.content); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GenFiles generates the schema files of all the files to generate of gen as Gen does, and of the messages as
+// GenMessage does.
+//
+// The files and messages, and the messages of each file, are converted by up to the parallelism parameter of
+// conversions at once, GOMAXPROCS by default. The outputs are added to the response in the order of gen.Files
+// followed by messages regardless of the order the conversions finish in, so the response is the same for any
+// parallelism.
+func GenFiles(gen *protogen.Plugin, messages ...protoreflect.MessageDescriptor) error {
+	defer log.Sync()
+
+	opts := parseOptions(gen.Request.GetParameter())
+
+	// the registry of the request is shared by the workers rather than built for each file, which would register the
+	// common imports again and again
+	fds := make([]protoreflect.FileDescriptor, len(gen.Files))
+	for i, file := range gen.Files {
+		fds[i] = file.Desc
+	}
+	files, err := newFiles(fds...)
+	if err != nil {
+		return err
+	}
+	opts.Files = files
+	// the conversions of the messages of the files share the limit of the files, rather than multiplying it
+	opts.limiter = newLimiter(opts.Parallelism)
+
+	var jobs []func() (*output, error)
+	for _, file := range gen.Files {
+		if !file.Generate {
+			continue
+		}
+		file := file
+		jobs = append(jobs, func() (*output, error) {
+			return renderFile(gen, file, opts)
+		})
+	}
+	for _, md := range messages {
+		md := md
+		jobs = append(jobs, func() (*output, error) {
+			return renderMessage(gen, md, opts)
+		})
+	}
+
+	outs := make([]*output, len(jobs))
+	errs := make([]error, len(jobs))
+	opts.limiter.run(len(jobs), func(i int) {
+		outs[i], errs[i] = jobs[i]()
+	})
+
+	for i, out := range outs {
+		if errs[i] != nil {
+			continue
+		}
+		if err := out.write(gen); err != nil {
+			errs[i] = err
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	log.Debug("succeeded to process code generator request")
+	return nil
+}
+
+// defineMessages adds the definitions of the messages and the messages they refer to, which are converted by up to
+// the Parallelism option of conversions at once.
+//
+// The messages to define are collected beforehand, and each of them is converted by a converter of its own which only
+// refers to the others. The definitions and the errors are merged in the order of the collected messages, so they are
+// the same for any parallelism.
+func (c *converter) defineMessages(messages []protoreflect.MessageDescriptor) {
+	collected := c.collectMessages(messages)
+
+	referred := make(map[string]bool, len(collected))
+	for _, msg := range collected {
+		referred[string(msg.FullName())] = true
+	}
+
+	l := c.opts.limiter
+	if l == nil {
+		l = newLimiter(c.opts.Parallelism)
+	}
+	converters := make([]*converter, len(collected))
+	l.run(len(collected), func(i int) {
+		msg := collected[i]
+		w := newConverter(c.opts, c.files)
+		w.referred = referred
+		messageJSONSchema, err := w.convertMessageType(msg)
+		if err != nil {
+			w.addError(msg, err)
+		} else {
+			w.definitions[string(msg.FullName())] = &messageJSONSchema
+		}
+		converters[i] = w
+	})
+
+	for _, w := range converters {
+		for name, def := range w.definitions {
+			// the enums are defined by all the converters of the messages which refer to them alike
+			if _, ok := c.definitions[name]; !ok {
+				c.definitions[name] = def
+			}
+		}
+		c.errs = append(c.errs, w.errs...)
+	}
+	for name := range referred {
+		c.seen[name] = true
+	}
+}
+
+// collectMessages returns the messages and the ones their fields refer to transitively, which are not seen by c yet,
+// in the depth-first order. The well-known types are left out, as they are not defined.
+//
+// The fields which fail to resolve are skipped, and reported by their conversion.
+func (c *converter) collectMessages(messages []protoreflect.MessageDescriptor) []protoreflect.MessageDescriptor {
+	var collected []protoreflect.MessageDescriptor
+	visited := make(map[string]bool)
+
+	var visit func(msg protoreflect.MessageDescriptor)
+	visit = func(msg protoreflect.MessageDescriptor) {
+		name := string(msg.FullName())
+		if c.seen[name] || visited[name] || wellKnownTypes[msg.FullName()] != nil || wrapperTypes[msg.FullName()] {
+			return
+		}
+		visited[name] = true
+		collected = append(collected, msg)
+
+		fields := msg.Fields()
+		for i := 0; i < fields.Len(); i++ {
+			field := fields.Get(i)
+			if hidden(field) {
+				continue
+			}
+			if field.IsMap() {
+				field = field.MapValue()
+			}
+			if field.Message() == nil {
+				continue
+			}
+			if ref, err := c.resolveMessage(field); err == nil {
+				visit(ref)
+			}
+		}
+	}
+	for _, msg := range messages {
+		visit(msg)
+	}
+
+	return collected
+}
+
+// limiter bounds the number of the calls running at once, which are shared by the nested runs, so that a run inside
+// the calls of another run does not multiply the limit.
+type limiter chan struct{}
+
+// newLimiter returns the limiter of parallelism calls at once, or GOMAXPROCS calls if parallelism is not positive.
+func newLimiter(parallelism int) limiter {
+	if parallelism <= 0 {
+		parallelism = runtime.GOMAXPROCS(0)
+	}
+
+	// the goroutine of the run calls f too, so it has one of the slots
+	return make(limiter, parallelism-1)
+}
+
+// run calls f for each index in [0, n) and waits for all of them. Each call runs in a goroutine of its own if l has
+// a free slot, or in the goroutine of the run otherwise, which never waits for the slots held by the outer runs.
+func (l limiter) run(n int, f func(i int)) {
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		select {
+		case l <- struct{}{}:
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				defer func() { <-l }()
+				f(i)
+			}(i)
+		default:
+			f(i)
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,260 @@
+// Copyright 2019 The protoc-gen-jsonschema Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genjsonschema
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// syntheticFiles returns the files of a synthetic large descriptor set, where each file has the messages whose
+// fields refer to the messages of the same file, and the last of which refers to the first message of the file it
+// imports.
+func syntheticFiles(numFiles, numMessages int) []*descriptorpb.FileDescriptorProto {
+	field := func(name string, number int32, label descriptorpb.FieldDescriptorProto_Label, typ descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(name),
+			Number:   proto.Int32(number),
+			Label:    label.Enum(),
+			Type:     typ.Enum(),
+			JsonName: proto.String(name),
+		}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+	const (
+		optional = descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+		repeated = descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+	)
+
+	files := make([]*descriptorpb.FileDescriptorProto, numFiles)
+	for i := range files {
+		pkg := fmt.Sprintf("synthetic.p%d", i)
+		file := &descriptorpb.FileDescriptorProto{
+			Name:    proto.String(fmt.Sprintf("synthetic/file%d.proto", i)),
+			Package: proto.String(pkg),
+			Syntax:  proto.String("proto3"),
+			Options: &descriptorpb.FileOptions{GoPackage: proto.String("example.com/synthetic/" + pkg)},
+			EnumType: []*descriptorpb.EnumDescriptorProto{{
+				Name: proto.String("Kind"),
+				Value: []*descriptorpb.EnumValueDescriptorProto{
+					{Name: proto.String("KIND_UNSPECIFIED"), Number: proto.Int32(0)},
+					{Name: proto.String("KIND_LEAF"), Number: proto.Int32(1)},
+				},
+			}},
+		}
+		if i > 0 {
+			file.Dependency = []string{files[i-1].GetName()}
+		}
+
+		for j := 0; j < numMessages; j++ {
+			name := fmt.Sprintf("Message%d", j)
+			msg := &descriptorpb.DescriptorProto{
+				Name: proto.String(name),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("name", 1, optional, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("count", 2, optional, descriptorpb.FieldDescriptorProto_TYPE_INT64, ""),
+					field("kind", 3, optional, descriptorpb.FieldDescriptorProto_TYPE_ENUM, "."+pkg+".Kind"),
+					field("children", 4, repeated, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, "."+pkg+"."+name),
+					field("attrs", 5, repeated, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, "."+pkg+"."+name+".AttrsEntry"),
+					field("text", 6, optional, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					field("number", 7, optional, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, ""),
+				},
+				NestedType: []*descriptorpb.DescriptorProto{{
+					Name:    proto.String("AttrsEntry"),
+					Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+					Field: []*descriptorpb.FieldDescriptorProto{
+						field("key", 1, optional, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+						field("value", 2, optional, descriptorpb.FieldDescriptorProto_TYPE_STRING, ""),
+					},
+				}},
+				OneofDecl: []*descriptorpb.OneofDescriptorProto{{Name: proto.String("value")}},
+			}
+			msg.Field[5].OneofIndex = proto.Int32(0)
+			msg.Field[6].OneofIndex = proto.Int32(0)
+			if j > 0 {
+				msg.Field = append(msg.Field, field("previous", 8, optional, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, fmt.Sprintf(".%s.Message%d", pkg, j-1)))
+			}
+			if j == numMessages-1 && i > 0 {
+				// the first messages refer to no other files, so the imports are not resolved transitively
+				msg.Field = append(msg.Field, field("imported", 9, optional, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, fmt.Sprintf(".synthetic.p%d.Message0", i-1)))
+			}
+			file.MessageType = append(file.MessageType, msg)
+		}
+		files[i] = file
+	}
+
+	return files
+}
+
+// newPlugin returns the plugin of the request to generate all the files.
+func newPlugin(tb testing.TB, parameter string, files []*descriptorpb.FileDescriptorProto) *protogen.Plugin {
+	tb.Helper()
+
+	req := &pluginpb.CodeGeneratorRequest{
+		Parameter: proto.String(parameter),
+		ProtoFile: files,
+	}
+	for _, file := range files {
+		req.FileToGenerate = append(req.FileToGenerate, file.GetName())
+	}
+	gen, err := protogen.Options{}.New(req)
+	if err != nil {
+		tb.Fatalf("protogen.Options.New: %v", err)
+	}
+
+	return gen
+}
+
+// genFilesResponse runs GenFiles for all the files and returns the response.
+func genFilesResponse(tb testing.TB, parameter string, files []*descriptorpb.FileDescriptorProto) *pluginpb.CodeGeneratorResponse {
+	tb.Helper()
+
+	gen := newPlugin(tb, parameter, files)
+	if err := GenFiles(gen); err != nil {
+		gen.Error(err)
+	}
+
+	return gen.Response()
+}
+
+func TestGenFilesParallelism(t *testing.T) {
+	for _, tt := range []struct {
+		name                  string
+		numFiles, numMessages int
+	}{
+		{name: "many files", numFiles: 20, numMessages: 10},
+		// the messages of a file are converted in parallel too
+		{name: "huge files", numFiles: 2, numMessages: 200},
+	} {
+		files := syntheticFiles(tt.numFiles, tt.numMessages)
+		for _, format := range []string{outputFormatJSONSchema, outputFormatOpenAPI31} {
+			format := format
+			t.Run(tt.name+"/"+format, func(t *testing.T) {
+				want := genFilesResponse(t, "output_format="+format+",parallelism=1", files)
+				if want.Error != nil {
+					t.Fatalf("GenFiles: %s", want.GetError())
+				}
+				if len(want.GetFile()) != len(files) {
+					t.Fatalf("generated %d files, want %d", len(want.GetFile()), len(files))
+				}
+				for i, f := range want.GetFile() {
+					if wantPrefix := strings.TrimSuffix(files[i].GetName(), ".proto"); !strings.HasPrefix(f.GetName(), "synthetic/") || (format == outputFormatJSONSchema && !strings.HasPrefix(f.GetName(), wantPrefix)) {
+						t.Errorf("file %d is %s, want the output of %s", i, f.GetName(), files[i].GetName())
+					}
+				}
+
+				for _, parallelism := range []string{"2", "8", "64"} {
+					got := genFilesResponse(t, "output_format="+format+",parallelism="+parallelism, files)
+					if !proto.Equal(got, want) {
+						t.Errorf("parallelism=%s: the response differs from parallelism=1", parallelism)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	for _, tt := range []struct {
+		outer, inner, parallelism int
+	}{
+		{outer: 0, inner: 0, parallelism: 4},
+		{outer: 1, inner: 0, parallelism: 0},
+		{outer: 10, inner: 0, parallelism: 1},
+		{outer: 100, inner: 0, parallelism: 7},
+		// the runs in the calls of a run share its limit
+		{outer: 8, inner: 50, parallelism: 4},
+		{outer: 3, inner: 20, parallelism: 1},
+	} {
+		var calls, running, maxRunning int32
+		call := func() {
+			r := atomic.AddInt32(&running, 1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if r <= m || atomic.CompareAndSwapInt32(&maxRunning, m, r) {
+					break
+				}
+			}
+			atomic.AddInt32(&calls, 1)
+			atomic.AddInt32(&running, -1)
+		}
+
+		l := newLimiter(tt.parallelism)
+		done := make([]bool, tt.outer)
+		l.run(tt.outer, func(i int) {
+			if tt.inner == 0 {
+				call()
+			} else {
+				l.run(tt.inner, func(int) { call() })
+			}
+			done[i] = true
+		})
+
+		want := tt.outer
+		if tt.inner > 0 {
+			want *= tt.inner
+		}
+		if int(calls) != want {
+			t.Errorf("%+v: called f %d times, want %d", tt, calls, want)
+		}
+		for i, ok := range done {
+			if !ok {
+				t.Errorf("%+v: did not call f(%d)", tt, i)
+			}
+		}
+		if tt.parallelism > 0 && int(maxRunning) > tt.parallelism {
+			t.Errorf("%+v: ran %d calls at once", tt, maxRunning)
+		}
+	}
+}
+
+func BenchmarkGenFiles(b *testing.B) {
+	atom.SetLevel(zap.WarnLevel)
+	defer atom.SetLevel(zap.InfoLevel)
+
+	for _, shape := range []struct {
+		name                  string
+		numFiles, numMessages int
+	}{
+		{name: "200x20", numFiles: 200, numMessages: 20},
+		// a few huge files, whose messages are converted in parallel
+		{name: "4x1000", numFiles: 4, numMessages: 1000},
+	} {
+		files := syntheticFiles(shape.numFiles, shape.numMessages)
+		for _, bb := range []struct {
+			name      string
+			parameter string
+		}{
+			{name: "parallelism=1", parameter: "parallelism=1"},
+			{name: "parallelism=4", parameter: "parallelism=4"},
+			{name: "parallelism=GOMAXPROCS", parameter: ""},
+		} {
+			bb := bb
+			b.Run(shape.name+"/"+bb.name, func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					b.StopTimer()
+					gen := newPlugin(b, bb.parameter, files)
+					b.StartTimer()
+
+					if err := GenFiles(gen); err != nil {
+						b.Fatalf("GenFiles: %v", err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2019 The protoc-gen-jsonschema Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genjsonschema
+
+import (
+	pgv "github.com/envoyproxy/protoc-gen-validate/validate"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// validateDisabled reports whether the protoc-gen-validate rules of the fields of the message are turned off by the
+// "validate.disabled" or "validate.ignored" option.
+func validateDisabled(msg protoreflect.MessageDescriptor) bool {
+	disabled, _ := getExtension(msg, pgv.E_Disabled).(bool)
+	ignored, _ := getExtension(msg, pgv.E_Ignored).(bool)
+
+	return disabled || ignored
+}
+
+// validateOneofRequired reports whether the "validate.required" option of the oneof requires one of its fields.
+func validateOneofRequired(oneof protoreflect.OneofDescriptor) bool {
+	required, _ := getExtension(oneof, pgv.E_Required).(bool)
+
+	return required
+}
+
+// applyValidateRules adds the constraints of the protoc-gen-validate rules of the field, its "validate.rules"
+// option, to the schema t of the field, and requires the field in the message schema msgType if the rules do.
+//
+// It returns the paths of the rules which have no JSON Schema equivalent, e.g. "bytes.min_len", which are not
+// reflected in the schemas.
+func (c *converter) applyValidateRules(msgType, t *Type, field protoreflect.FieldDescriptor) []string {
+	rules, ok := getExtension(field, pgv.E_Rules).(*pgv.FieldRules)
+	if !ok {
+		return nil
+	}
+
+	r := &fieldRules{c: c}
+	r.field(t, field, rules.ProtoReflect(), c.acceptsNull(field), "")
+	if r.required {
+		c.require(msgType, field)
+	}
+
+	return r.unsupported
+}
@@ -0,0 +1,138 @@
+// Copyright 2019 The protoc-gen-jsonschema Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genjsonschema
+
+import (
+	"strings"
+	"testing"
+
+	pgv "github.com/envoyproxy/protoc-gen-validate/validate"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// observeWarnings replaces the logger by the one which records the warnings until the test finishes.
+func observeWarnings(t *testing.T) *observer.ObservedLogs {
+	t.Helper()
+
+	core, logs := observer.New(zap.WarnLevel)
+	l := log
+	log = zap.New(core).Sugar()
+	t.Cleanup(func() { log = l })
+
+	return logs
+}
+
+func TestGenValidateRules(t *testing.T) {
+	file := fileDescriptorProto(t, `
+name: "users.proto" package: "test.users" syntax: "proto3"
+dependency: "validate/validate.proto"
+options { go_package: "example.com/test/users" }
+enum_type { name: "Kind" value { name: "KIND_UNSPECIFIED" number: 0 } value { name: "KIND_ADMIN" number: 1 } value { name: "KIND_GUEST" number: 2 } }
+message_type {
+  name: "User"
+  field { name: "name" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "name" options { [validate.rules] { string { min_len: 1 max_len: 5 pattern: "^[a-z]+$" } } } }
+  field { name: "email" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "email" options { [validate.rules] { string { email: true } } } }
+  field { name: "ip" number: 3 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "ip" options { [validate.rules] { string { ip: true } } } }
+  field { name: "age" number: 4 label: LABEL_OPTIONAL type: TYPE_INT32 json_name: "age" options { [validate.rules] { int32 { gte: 0 lt: 150 } } } }
+  field { name: "score" number: 5 label: LABEL_OPTIONAL type: TYPE_INT64 json_name: "score" options { [validate.rules] { int64 { gt: 10 lt: 5 } } } }
+  field { name: "ratio" number: 6 label: LABEL_OPTIONAL type: TYPE_DOUBLE json_name: "ratio" options { [validate.rules] { double { in: [0.5, 1.5] } } } }
+  field { name: "code" number: 7 label: LABEL_OPTIONAL type: TYPE_UINT32 json_name: "code" options { [validate.rules] { uint32 { not_in: 3 } } } }
+  field { name: "agreed" number: 8 label: LABEL_OPTIONAL type: TYPE_BOOL json_name: "agreed" options { [validate.rules] { bool { const: true } } } }
+  field { name: "kind" number: 9 label: LABEL_OPTIONAL type: TYPE_ENUM type_name: ".test.users.Kind" json_name: "kind" options { [validate.rules] { enum { defined_only: true not_in: 0 } } } }
+  field { name: "tags" number: 10 label: LABEL_REPEATED type: TYPE_STRING json_name: "tags" options { [validate.rules] { repeated { min_items: 1 max_items: 3 unique: true items { string { prefix: "t-" } } } } } }
+  field { name: "counts" number: 11 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".test.users.User.CountsEntry" json_name: "counts" options { [validate.rules] { map { max_pairs: 2 keys { string { max_len: 3 } } values { int32 { gt: 0 } } } } } }
+  field { name: "address" number: 12 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".test.users.Address" json_name: "address" options { [validate.rules] { message { required: true } } } }
+  field { name: "avatar" number: 13 label: LABEL_OPTIONAL type: TYPE_BYTES json_name: "avatar" options { [validate.rules] { bytes { min_len: 1 } } } }
+  field { name: "id" number: 14 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "id" options { [validate.rules] { string { uuid: true min_bytes: 1 } } } }
+  field { name: "phone" number: 15 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "phone" oneof_index: 0 }
+  field { name: "fax" number: 16 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "fax" oneof_index: 0 }
+  nested_type {
+    name: "CountsEntry" options { map_entry: true }
+    field { name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "key" }
+    field { name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_INT32 json_name: "value" }
+  }
+  oneof_decl { name: "contact" options { [validate.required]: true } }
+}
+message_type {
+  name: "Address"
+  options { [validate.disabled]: true }
+  field { name: "city" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "city" options { [validate.rules] { string { min_len: 1 } } } }
+}`)
+	files := append(linkedFiles(pgv.File_validate_validate_proto), file)
+
+	tests := []struct {
+		document string
+		valid    bool
+	}{
+		{document: `{"address": {}, "phone": "1"}`, valid: true},
+		{document: `{"address": {}, "fax": "1", "name": "abc", "email": "a@example.com", "ip": "::1", "age": 0, "score": 11, "ratio": 1.5, "code": 4, "agreed": true, "kind": "KIND_ADMIN", "tags": ["t-a", "t-b"], "counts": {"a": 1}, "avatar": "", "id": "8c8f2b7e-3f0a-4d5e-9a4b-6f1d2c3b4a5e"}`, valid: true},
+		{document: `{"address": {}, "phone": "1", "score": 4, "kind": 2}`, valid: true},
+		{document: `{"address": {"city": ""}, "phone": "1"}`, valid: true},
+		{document: `{"phone": "1"}`, valid: false},
+		{document: `{"address": {}}`, valid: false},
+		{document: `{"address": {}, "phone": "1", "name": ""}`, valid: false},
+		{document: `{"address": {}, "phone": "1", "name": "abcdef"}`, valid: false},
+		{document: `{"address": {}, "phone": "1", "name": "ABC"}`, valid: false},
+		{document: `{"address": {}, "phone": "1", "email": "a"}`, valid: false},
+		{document: `{"address": {}, "phone": "1", "ip": "localhost"}`, valid: false},
+		{document: `{"address": {}, "phone": "1", "age": -1}`, valid: false},
+		{document: `{"address": {}, "phone": "1", "age": 150}`, valid: false},
+		{document: `{"address": {}, "phone": "1", "score": 7}`, valid: false},
+		{document: `{"address": {}, "phone": "1", "ratio": 1}`, valid: false},
+		{document: `{"address": {}, "phone": "1", "code": 3}`, valid: false},
+		{document: `{"address": {}, "phone": "1", "agreed": false}`, valid: false},
+		{document: `{"address": {}, "phone": "1", "kind": "KIND_UNSPECIFIED"}`, valid: false},
+		{document: `{"address": {}, "phone": "1", "kind": 0}`, valid: false},
+		{document: `{"address": {}, "phone": "1", "kind": 3}`, valid: false},
+		{document: `{"address": {}, "phone": "1", "tags": []}`, valid: false},
+		{document: `{"address": {}, "phone": "1", "tags": ["t-a", "t-a"]}`, valid: false},
+		{document: `{"address": {}, "phone": "1", "tags": ["t-a", "t-b", "t-c", "t-d"]}`, valid: false},
+		{document: `{"address": {}, "phone": "1", "tags": ["a"]}`, valid: false},
+		{document: `{"address": {}, "phone": "1", "counts": {"a": 1, "b": 1, "c": 1}}`, valid: false},
+		{document: `{"address": {}, "phone": "1", "counts": {"abcd": 1}}`, valid: false},
+		{document: `{"address": {}, "phone": "1", "counts": {"a": 0}}`, valid: false},
+		{document: `{"address": {}, "phone": "1", "id": "1"}`, valid: false},
+	}
+	for _, parameter := range []string{"draft=07", "draft=07,oneof_encoding=lenient", "draft=07,allow_null_values"} {
+		parameter := parameter
+		t.Run(parameter, func(t *testing.T) {
+			logs := observeWarnings(t)
+			schema := generate(t, parameter, files...)["users.jsonschema"]
+
+			for _, tt := range tests {
+				errs := validate(t, schema, "test.users.User", tt.document)
+				if valid := len(errs) == 0; valid != tt.valid {
+					t.Errorf("%s: valid = %t, want %t: %v", tt.document, valid, tt.valid, errs)
+				}
+			}
+
+			var warnings []string
+			for _, entry := range logs.All() {
+				warnings = append(warnings, entry.Message)
+			}
+			for _, want := range []string{
+				"test.users.User.avatar which have no JSON Schema equivalent: bytes.min_len",
+				"test.users.User.id which have no JSON Schema equivalent: string.min_bytes",
+			} {
+				if !strings.Contains(strings.Join(warnings, "\n"), want) {
+					t.Errorf("warnings = %q, want %q", warnings, want)
+				}
+			}
+			if len(warnings) != 2 {
+				t.Errorf("warnings = %q, want 2", warnings)
+			}
+		})
+	}
+
+	t.Run("allow_null_values", func(t *testing.T) {
+		observeWarnings(t)
+		schema := generate(t, "allow_null_values", files...)["users.jsonschema"]
+		document := `{"address": {}, "phone": "1", "ratio": null, "agreed": null, "kind": null}`
+		if errs := validate(t, schema, "test.users.User", document); len(errs) > 0 {
+			t.Errorf("%s is not valid: %v", document, errs)
+		}
+	})
+}
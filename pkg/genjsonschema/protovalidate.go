@@ -0,0 +1,99 @@
+// Copyright 2019 The protoc-gen-jsonschema Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genjsonschema
+
+import (
+	protovalidate "buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// celRule is a CEL expression of the protovalidate rules, which JSON Schema can not evaluate. The expressions are
+// written in the "x-cel" extension of the schemas, so that the editors can show them.
+type celRule struct {
+	ID         string `json:"id,omitempty"`
+	Message    string `json:"message,omitempty"`
+	Expression string `json:"expression"`
+}
+
+// addCEL adds the CEL rules to the "x-cel" extension of the schema t, and their messages to the description of t.
+func addCEL(t *Type, rules []*protovalidate.Rule) {
+	if t.Extensions == nil {
+		t.Extensions = make(map[string]interface{})
+	}
+	cel, _ := t.Extensions["x-cel"].([]celRule)
+	for _, rule := range rules {
+		cel = append(cel, celRule{
+			ID:         rule.GetId(),
+			Message:    rule.GetMessage(),
+			Expression: rule.GetExpression(),
+		})
+		if rule.GetMessage() == "" {
+			continue
+		}
+		if t.Description != "" {
+			t.Description += "\n\n"
+		}
+		t.Description += rule.GetMessage()
+	}
+	t.Extensions["x-cel"] = cel
+}
+
+// ignoredAlways reports whether the FieldRules turn off all the rules of the field by "ignore: IGNORE_ALWAYS".
+func ignoredAlways(rules protoreflect.Message) bool {
+	ignore := rules.Descriptor().Fields().ByName("ignore")
+	return ignore != nil && rules.Get(ignore).Enum() == protoreflect.EnumNumber(protovalidate.Ignore_IGNORE_ALWAYS)
+}
+
+// protovalidateOneofRequired reports whether the "buf.validate.oneof" option of the oneof requires one of its fields.
+func protovalidateOneofRequired(oneof protoreflect.OneofDescriptor) bool {
+	rules, ok := getExtension(oneof, protovalidate.E_Oneof).(*protovalidate.OneofRules)
+	return ok && rules.GetRequired()
+}
+
+// applyProtovalidateRules adds the constraints of the protovalidate rules of the field, its "buf.validate.field"
+// option, to the schema t of the field, and requires the field in the message schema msgType if the rules do.
+//
+// It returns the paths of the rules which have no JSON Schema equivalent, e.g. "timestamp.lt_now", which are not
+// reflected in the schemas.
+func (c *converter) applyProtovalidateRules(msgType, t *Type, field protoreflect.FieldDescriptor) []string {
+	rules, ok := getExtension(field, protovalidate.E_Field).(*protovalidate.FieldRules)
+	if !ok {
+		return nil
+	}
+
+	r := &fieldRules{c: c}
+	r.field(t, field, rules.ProtoReflect(), c.acceptsNull(field), "")
+	if r.required && !r.ignoreZero {
+		c.require(msgType, field)
+	}
+
+	return r.unsupported
+}
+
+// applyProtovalidateMessageRules adds the protovalidate rules of the message, its "buf.validate.message" option, to
+// the message schema t: the CEL rules as the "x-cel" extension, and the oneof rules as the constraints of the proto
+// "oneof".
+func (c *converter) applyProtovalidateMessageRules(t *Type, msg protoreflect.MessageDescriptor) {
+	rules, ok := getExtension(msg, protovalidate.E_Message).(*protovalidate.MessageRules)
+	if !ok {
+		return
+	}
+
+	if len(rules.GetCel()) > 0 {
+		addCEL(t, rules.GetCel())
+	}
+
+	for _, oneof := range rules.GetOneof() {
+		fields := make([]protoreflect.FieldDescriptor, 0, len(oneof.GetFields()))
+		for _, name := range oneof.GetFields() {
+			if field := msg.Fields().ByName(protoreflect.Name(name)); field != nil {
+				fields = append(fields, field)
+			}
+		}
+		if len(fields) > 0 {
+			c.convertOneof(fields, t, oneof.GetRequired())
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2019 The protoc-gen-jsonschema Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genjsonschema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	protovalidate "buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestGenProtovalidateRules(t *testing.T) {
+	file := fileDescriptorProto(t, `
+name: "orders.proto" package: "test.orders" syntax: "proto3"
+dependency: ["buf/validate/validate.proto", "google/protobuf/timestamp.proto"]
+options { go_package: "example.com/test/orders" }
+message_type {
+  name: "Order"
+  options { [buf.validate.message] { cel { id: "order.window" message: "ships after it is placed" expression: "this.ship_by > this.placed_at" } oneof { fields: ["email", "phone"] required: true } } }
+  field { name: "id" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "id" options { [buf.validate.field] { required: true string { uuid: true } } } }
+  field { name: "quantity" number: 2 label: LABEL_OPTIONAL type: TYPE_INT32 json_name: "quantity" options { [buf.validate.field] { int32 { gt: 0 lte: 100 } } } }
+  field { name: "note" number: 3 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "note" options { [buf.validate.field] { ignore: IGNORE_ALWAYS string { min_len: 10 } } } }
+  field { name: "coupon" number: 4 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "coupon" options { [buf.validate.field] { required: true ignore: IGNORE_IF_ZERO_VALUE string { len: 8 } } } }
+  field { name: "items" number: 5 label: LABEL_REPEATED type: TYPE_STRING json_name: "items" options { [buf.validate.field] { repeated { min_items: 1 items { string { tuuid: true } } } } } }
+  field { name: "even" number: 6 label: LABEL_OPTIONAL type: TYPE_INT64 json_name: "even" options { [buf.validate.field] { cel { id: "even" message: "must be even" expression: "this % 2 == 0" } } } }
+  field { name: "placed_at" number: 7 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Timestamp" json_name: "placedAt" options { [buf.validate.field] { timestamp { lt_now: true } } } }
+  field { name: "ship_by" number: 8 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".google.protobuf.Timestamp" json_name: "shipBy" }
+  field { name: "email" number: 9 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "email" options { [buf.validate.field] { string { email: true } } } }
+  field { name: "phone" number: 10 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "phone" }
+  field { name: "card" number: 11 label: LABEL_OPTIONAL type: TYPE_STRING json_name: "card" oneof_index: 0 }
+  field { name: "cash" number: 12 label: LABEL_OPTIONAL type: TYPE_BOOL json_name: "cash" oneof_index: 0 }
+  oneof_decl { name: "payment" options { [buf.validate.oneof] { required: true } } }
+}`)
+	files := append(linkedFiles(protovalidate.File_buf_validate_validate_proto, timestamppb.File_google_protobuf_timestamp_proto), file)
+
+	tests := []struct {
+		document string
+		valid    bool
+	}{
+		{document: `{"id": "8c8f2b7e-3f0a-4d5e-9a4b-6f1d2c3b4a5e", "email": "a@example.com", "cash": true}`, valid: true},
+		{document: `{"id": "8c8f2b7e-3f0a-4d5e-9a4b-6f1d2c3b4a5e", "phone": "1", "card": "x", "quantity": 100, "note": "short", "coupon": "ABCDEFGH", "items": ["0123456789abcdef0123456789ABCDEF"], "even": 1}`, valid: true},
+		{document: `{"email": "a@example.com", "cash": true}`, valid: false},
+		{document: `{"id": "1", "email": "a@example.com", "cash": true}`, valid: false},
+		{document: `{"id": "8c8f2b7e-3f0a-4d5e-9a4b-6f1d2c3b4a5e", "cash": true}`, valid: false},
+		{document: `{"id": "8c8f2b7e-3f0a-4d5e-9a4b-6f1d2c3b4a5e", "email": "a@example.com", "phone": "1", "cash": true}`, valid: false},
+		{document: `{"id": "8c8f2b7e-3f0a-4d5e-9a4b-6f1d2c3b4a5e", "email": "a@example.com"}`, valid: false},
+		{document: `{"id": "8c8f2b7e-3f0a-4d5e-9a4b-6f1d2c3b4a5e", "email": "a@example.com", "cash": true, "quantity": 0}`, valid: false},
+		{document: `{"id": "8c8f2b7e-3f0a-4d5e-9a4b-6f1d2c3b4a5e", "email": "a@example.com", "cash": true, "quantity": 101}`, valid: false},
+		{document: `{"id": "8c8f2b7e-3f0a-4d5e-9a4b-6f1d2c3b4a5e", "email": "a@example.com", "cash": true, "coupon": "ABC"}`, valid: false},
+		{document: `{"id": "8c8f2b7e-3f0a-4d5e-9a4b-6f1d2c3b4a5e", "email": "a@example.com", "cash": true, "items": []}`, valid: false},
+		{document: `{"id": "8c8f2b7e-3f0a-4d5e-9a4b-6f1d2c3b4a5e", "email": "a@example.com", "cash": true, "items": ["8c8f2b7e-3f0a-4d5e-9a4b-6f1d2c3b4a5e"]}`, valid: false},
+	}
+	for _, parameter := range []string{"draft=07", "draft=07,oneof_encoding=lenient"} {
+		parameter := parameter
+		t.Run(parameter, func(t *testing.T) {
+			logs := observeWarnings(t)
+			schema := generate(t, parameter, files...)["orders.jsonschema"]
+
+			for _, tt := range tests {
+				errs := validate(t, schema, "test.orders.Order", tt.document)
+				if valid := len(errs) == 0; valid != tt.valid {
+					t.Errorf("%s: valid = %t, want %t: %v", tt.document, valid, tt.valid, errs)
+				}
+			}
+
+			var warnings []string
+			for _, entry := range logs.All() {
+				warnings = append(warnings, entry.Message)
+			}
+			want := "ignoring the validation rules of test.orders.Order.placed_at which have no JSON Schema equivalent: timestamp.lt_now"
+			if len(warnings) != 1 || warnings[0] != want {
+				t.Errorf("warnings = %q, want %q", warnings, want)
+			}
+		})
+	}
+
+	t.Run("cel", func(t *testing.T) {
+		def := definitions(t, generate(t, "", files...)["orders.jsonschema"])["test.orders.Order"]
+
+		tests := []struct {
+			name            string
+			schema          map[string]interface{}
+			wantCEL         string
+			wantDescription string
+		}{
+			{
+				name:            "message",
+				schema:          def,
+				wantCEL:         `[{"id":"order.window","message":"ships after it is placed","expression":"this.ship_by > this.placed_at"}]`,
+				wantDescription: "ships after it is placed",
+			},
+			{
+				name:            "field",
+				schema:          def["properties"].(map[string]interface{})["even"].(map[string]interface{}),
+				wantCEL:         `[{"id":"even","message":"must be even","expression":"this % 2 == 0"}]`,
+				wantDescription: "must be even",
+			},
+		}
+		for _, tt := range tests {
+			var want interface{}
+			if err := json.Unmarshal([]byte(tt.wantCEL), &want); err != nil {
+				t.Fatalf("json.Unmarshal: %v", err)
+			}
+			if got := tt.schema["x-cel"]; !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: x-cel = %v, want %s", tt.name, got, tt.wantCEL)
+			}
+			if got := tt.schema["description"]; got != tt.wantDescription {
+				t.Errorf("%s: description = %v, want %q", tt.name, got, tt.wantDescription)
+			}
+		}
+	})
+}
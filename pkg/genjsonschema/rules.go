@@ -0,0 +1,400 @@
+// Copyright 2019 The protoc-gen-jsonschema Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genjsonschema
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"regexp"
+	"strconv"
+
+	protovalidate "buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// acceptsNull reports whether the schema of the field accepts null in addition to its values.
+func (c *converter) acceptsNull(field protoreflect.FieldDescriptor) bool {
+	return c.opts.AllowNullValues || (field.Message() != nil && wrapperTypes[field.Message().FullName()])
+}
+
+// fieldRules maps the rules of a field, the FieldRules of protoc-gen-validate or protovalidate, to the keywords of
+// its schema.
+//
+// The rules are read by their names through protoreflect, because protovalidate took over the names of the rules of
+// protoc-gen-validate, and the numeric rules of all the kinds only differ in the types of their values.
+type fieldRules struct {
+	c *converter
+
+	required    bool     // the field must be present
+	ignoreZero  bool     // the rules skip the zero value of the field, which lets the field be absent
+	unsupported []string // paths of the rules which have no JSON Schema equivalent
+}
+
+// rangeRules calls f for each rule which is set in the rules, in the order of the declaration.
+//
+// The examples of protovalidate are skipped, because they annotate the fields rather than constrain them.
+func rangeRules(rules protoreflect.Message, f func(rule protoreflect.FieldDescriptor, v protoreflect.Value)) {
+	fields := rules.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		if rule := fields.Get(i); rules.Has(rule) && rule.Name() != "example" {
+			f(rule, rules.Get(rule))
+		}
+	}
+}
+
+// unsupportedRules records all the rules which are set in the rules as unsupported.
+func (r *fieldRules) unsupportedRules(rules protoreflect.Message, path string) {
+	rangeRules(rules, func(rule protoreflect.FieldDescriptor, _ protoreflect.Value) {
+		r.unsupported = append(r.unsupported, path+string(rule.Name()))
+	})
+}
+
+// field maps the FieldRules of the field to the keywords of its schema t. The path is the prefix of the paths of the
+// rules, which is empty for the top-level rules of the field.
+func (r *fieldRules) field(t *Type, field protoreflect.FieldDescriptor, rules protoreflect.Message, nullable bool, path string) {
+	if ignoredAlways(rules) {
+		return
+	}
+
+	rangeRules(rules, func(rule protoreflect.FieldDescriptor, v protoreflect.Value) {
+		name := string(rule.Name())
+		switch name {
+		case "message":
+			// the elements of the repeated fields and the maps can not be absent, and "skip" turns off the rules of
+			// the message fields, which are not mapped anyway
+			if path == "" && ruleBool(v.Message(), "required") {
+				r.required = true
+			}
+		case "required":
+			if path == "" && v.Bool() {
+				r.required = true
+			}
+		case "ignore":
+			// the rules are applied to the present values, and protojson omits the zero values of the fields
+			// without presence
+			r.ignoreZero = path == "" && v.Enum() == protoreflect.EnumNumber(protovalidate.Ignore_IGNORE_IF_ZERO_VALUE)
+		case "cel":
+			if rules, ok := rules.Interface().(*protovalidate.FieldRules); ok {
+				addCEL(t, rules.GetCel())
+			}
+		case "repeated":
+			r.repeated(t, field, v.Message(), path+"repeated.")
+		case "map":
+			r.mapRules(t, field, v.Message(), path+"map.")
+		case "enum":
+			r.scalar(t, v.Message(), nullable, field.Enum(), path+"enum.")
+		case "any", "duration", "timestamp":
+			r.message(t, v.Message(), path, path+name+".")
+		case "bytes":
+			// the lengths and the patterns of bytes apply to the raw bytes rather than the base64 strings
+			r.unsupportedRules(v.Message(), path+"bytes.")
+		default:
+			r.scalar(t, v.Message(), nullable, nil, path+name+".")
+		}
+	})
+}
+
+// ruleBool returns the boolean rule named name of the rules.
+func ruleBool(rules protoreflect.Message, name protoreflect.Name) bool {
+	rule := rules.Descriptor().Fields().ByName(name)
+	return rule != nil && rules.Get(rule).Bool()
+}
+
+// repeated maps the RepeatedRules of the field to the keywords of the array schema t.
+func (r *fieldRules) repeated(t *Type, field protoreflect.FieldDescriptor, rules protoreflect.Message, path string) {
+	rangeRules(rules, func(rule protoreflect.FieldDescriptor, v protoreflect.Value) {
+		switch rule.Name() {
+		case "min_items":
+			t.MinItems = proto.Uint64(v.Uint())
+		case "max_items":
+			t.MaxItems = proto.Uint64(v.Uint())
+		case "unique":
+			t.UniqueItems = v.Bool()
+		case "items":
+			if t.Items != nil {
+				r.field(t.Items, field, v.Message(), r.c.acceptsNull(field), path+"items.")
+			}
+		default:
+			r.unsupported = append(r.unsupported, path+string(rule.Name()))
+		}
+	})
+}
+
+// mapRules maps the MapRules of the field to the keywords of the object schema t.
+func (r *fieldRules) mapRules(t *Type, field protoreflect.FieldDescriptor, rules protoreflect.Message, path string) {
+	rangeRules(rules, func(rule protoreflect.FieldDescriptor, v protoreflect.Value) {
+		switch rule.Name() {
+		case "min_pairs":
+			t.MinProperties = proto.Uint64(v.Uint())
+		case "max_pairs":
+			t.MaxProperties = proto.Uint64(v.Uint())
+		case "keys":
+			if field.MapKey().Kind() != ProtoTypeString {
+				// the keys of the other kinds are the strings of the values, which the rules do not see
+				r.unsupported = append(r.unsupported, path+"keys")
+				break
+			}
+			if t.PropertyNames == nil {
+				t.PropertyNames = &Type{}
+			}
+			r.field(t.PropertyNames, field.MapKey(), v.Message(), false, path+"keys.")
+		case "values":
+			if t.AdditionalProperties != nil {
+				r.field(t.AdditionalProperties, field.MapValue(), v.Message(), r.c.acceptsNull(field.MapValue()), path+"values.")
+			}
+		default:
+			r.unsupported = append(r.unsupported, path+string(rule.Name()))
+		}
+	})
+}
+
+// message maps the rules of the well-known message types, AnyRules, DurationRules and TimestampRules, to the
+// keywords of their schema t. The parent is the path of the FieldRules which hold the rules.
+func (r *fieldRules) message(t *Type, rules protoreflect.Message, parent, path string) {
+	rangeRules(rules, func(rule protoreflect.FieldDescriptor, v protoreflect.Value) {
+		switch {
+		case rule.Name() == "required":
+			if parent == "" && v.Bool() {
+				r.required = true
+			}
+		case rules.Descriptor().Name() == "AnyRules" && (rule.Name() == "in" || rule.Name() == "not_in"):
+			typeURLs := &Type{Enum: r.values(rule, v, nil)}
+			if rule.Name() == "not_in" {
+				typeURLs = &Type{Not: typeURLs}
+			}
+			properties := &Properties{}
+			properties.Set("@type", typeURLs)
+			t.AllOf = append(t.AllOf, &Type{Properties: properties})
+		default:
+			// the comparisons of the durations and the timestamps are not of their strings
+			r.unsupported = append(r.unsupported, path+string(rule.Name()))
+		}
+	})
+}
+
+// stringFormats is the formats of the well-known string rules. The rules of more than one format accept any of them.
+var stringFormats = map[protoreflect.Name][]string{
+	"email":    {"email"},
+	"hostname": {"hostname"},
+	"ip":       {"ipv4", "ipv6"},
+	"ipv4":     {"ipv4"},
+	"ipv6":     {"ipv6"},
+	"uri":      {"uri"},
+	"uri_ref":  {"uri-reference"},
+	"address":  {"hostname", "ipv4", "ipv6"},
+	"uuid":     {"uuid"},
+}
+
+// stringPatterns is the patterns of the well-known string rules which have no format.
+var stringPatterns = map[protoreflect.Name]string{
+	"tuuid": `^[0-9a-fA-F]{32}$`,
+}
+
+// scalar maps the rules of the scalar kinds, the numbers, bool, string and enum, to the keywords of the schema t. The
+// enum is the type of the values of the enum rules, or nil for the other kinds.
+//
+// The constraints of the values, such as "minimum" and "minLength", leave null and the strings of the 64-bit integers
+// alone, while "const" and "enum" include them if the schema accepts them.
+func (r *fieldRules) scalar(t *Type, rules protoreflect.Message, nullable bool, enum protoreflect.EnumDescriptor, path string) {
+	var lower, upper *Type
+	var lowerValue, upperValue json.Number
+	rangeRules(rules, func(rule protoreflect.FieldDescriptor, v protoreflect.Value) {
+		name := rule.Name()
+		switch name {
+		case "const", "in":
+			values := r.values(rule, v, enum)
+			if nullable {
+				values = append(values, nil)
+			}
+			r.restrict(t, values)
+		case "not_in":
+			r.c.unref(t)
+			t.AllOf = append(t.AllOf, &Type{Not: &Type{Enum: r.values(rule, v, enum)}})
+		case "gt", "gte", "lt", "lte":
+			n, ok := r.values(rule, v, nil)[0].(json.Number)
+			if !ok {
+				// NaN and the infinities are the strings in JSON
+				r.unsupported = append(r.unsupported, path+string(name))
+				break
+			}
+			switch name {
+			case "gt":
+				lower, lowerValue = &Type{ExclusiveMinimum: n}, n
+			case "gte":
+				lower, lowerValue = &Type{Minimum: n}, n
+			case "lt":
+				upper, upperValue = &Type{ExclusiveMaximum: n}, n
+			case "lte":
+				upper, upperValue = &Type{Maximum: n}, n
+			}
+		case "len":
+			t.MinLength, t.MaxLength = proto.Uint64(v.Uint()), proto.Uint64(v.Uint())
+		case "min_len":
+			t.MinLength = proto.Uint64(v.Uint())
+		case "max_len":
+			t.MaxLength = proto.Uint64(v.Uint())
+		case "pattern":
+			addPattern(t, v.String())
+		case "prefix":
+			addPattern(t, "^"+regexp.QuoteMeta(v.String()))
+		case "suffix":
+			addPattern(t, regexp.QuoteMeta(v.String())+"$")
+		case "contains":
+			addPattern(t, regexp.QuoteMeta(v.String()))
+		case "not_contains":
+			t.AllOf = append(t.AllOf, &Type{Not: &Type{Pattern: regexp.QuoteMeta(v.String())}})
+		case "defined_only", "finite", "strict":
+			// the definitions of the enums accept only the defined values already, the schemas of the numbers
+			// accept neither NaN nor the infinities, which are the strings, and "strict" modifies
+			// "well_known_regex", which is not mapped
+		default:
+			if pattern, ok := stringPatterns[name]; ok {
+				if v.Bool() {
+					addPattern(t, pattern)
+				}
+				break
+			}
+			formats, ok := stringFormats[name]
+			switch {
+			case !ok:
+				r.unsupported = append(r.unsupported, path+string(name))
+			case !v.Bool():
+			case len(formats) == 1 && t.Format == "":
+				t.Format = formats[0]
+			default:
+				anyOf := make([]*Type, len(formats))
+				for i, format := range formats {
+					anyOf[i] = &Type{Format: format}
+				}
+				t.AllOf = append(t.AllOf, &Type{AnyOf: anyOf})
+			}
+		}
+	})
+
+	switch {
+	case lower != nil && upper != nil && greater(lowerValue, upperValue):
+		// the bounds in the reverse order exclude the range between them
+		t.AllOf = append(t.AllOf, &Type{AnyOf: []*Type{lower, upper}})
+	default:
+		if lower != nil {
+			t.Minimum, t.ExclusiveMinimum = lower.Minimum, lower.ExclusiveMinimum
+		}
+		if upper != nil {
+			t.Maximum, t.ExclusiveMaximum = upper.Maximum, upper.ExclusiveMaximum
+		}
+	}
+}
+
+// values returns the JSON values of the rule value v, which is a list for the repeated rules such as "in". The enum
+// is the type of the values of the enum rules, whose values are both the names and the numbers.
+func (r *fieldRules) values(rule protoreflect.FieldDescriptor, v protoreflect.Value, enum protoreflect.EnumDescriptor) []interface{} {
+	if rule.IsList() {
+		var values []interface{}
+		list := v.List()
+		for i := 0; i < list.Len(); i++ {
+			values = append(values, r.scalarValues(rule.Kind(), list.Get(i), enum)...)
+		}
+		return values
+	}
+
+	return r.scalarValues(rule.Kind(), v, enum)
+}
+
+// scalarValues returns the JSON values a value of the kind is encoded in by protojson.
+func (r *fieldRules) scalarValues(kind protoreflect.Kind, v protoreflect.Value, enum protoreflect.EnumDescriptor) []interface{} {
+	if enum != nil {
+		var values []interface{}
+		enumValues := enum.Values()
+		for i := 0; i < enumValues.Len(); i++ {
+			if enumValue := enumValues.Get(i); enumValue.Number() == protoreflect.EnumNumber(v.Int()) {
+				values = append(values, string(enumValue.Name()))
+			}
+		}
+		return append(values, json.Number(strconv.FormatInt(v.Int(), 10)))
+	}
+
+	switch kind {
+	case ProtoTypeBool:
+		return []interface{}{v.Bool()}
+	case ProtoTypeString:
+		return []interface{}{v.String()}
+	case ProtoTypeFloat, ProtoTypeDouble:
+		f := v.Float()
+		switch {
+		case math.IsNaN(f):
+			return []interface{}{"NaN"}
+		case math.IsInf(f, 1):
+			return []interface{}{"Infinity"}
+		case math.IsInf(f, -1):
+			return []interface{}{"-Infinity"}
+		}
+		bitSize := 64
+		if kind == ProtoTypeFloat {
+			bitSize = 32
+		}
+		return []interface{}{json.Number(strconv.FormatFloat(f, 'g', -1, bitSize))}
+	case ProtoTypeInt32, ProtoTypeSint32, ProtoTypeSfixed32:
+		return []interface{}{json.Number(strconv.FormatInt(v.Int(), 10))}
+	case ProtoTypeUint32, ProtoTypeFixed32:
+		return []interface{}{json.Number(strconv.FormatUint(v.Uint(), 10))}
+	}
+
+	// the 64-bit integers are encoded as the strings by protojson
+	var s string
+	switch kind {
+	case ProtoTypeUint64, ProtoTypeFixed64:
+		s = strconv.FormatUint(v.Uint(), 10)
+	default:
+		s = strconv.FormatInt(v.Int(), 10)
+	}
+	if r.c.opts.DisallowBigIntsAsStrings {
+		return []interface{}{json.Number(s)}
+	}
+	return []interface{}{json.Number(s), s}
+}
+
+// restrict restricts the values of the schema t to the values, by "const" if there is only one of them.
+func (r *fieldRules) restrict(t *Type, values []interface{}) {
+	r.c.unref(t)
+
+	restriction := &Type{Enum: values}
+	if len(values) == 1 {
+		restriction = &Type{Const: values[0]}
+	}
+	if t.Const != nil || t.Enum != nil {
+		t.AllOf = append(t.AllOf, restriction)
+		return
+	}
+	t.Const, t.Enum = restriction.Const, restriction.Enum
+}
+
+// unref moves the reference of the schema t into "allOf" before the keywords are added to t, because the siblings of
+// "$ref" are ignored until 2019-09.
+func (c *converter) unref(t *Type) {
+	if t.Ref == "" || c.opts.Draft >= Draft201909 {
+		return
+	}
+	t.AllOf = append(t.AllOf, &Type{Ref: t.Ref})
+	t.Ref = ""
+}
+
+// addPattern adds the pattern to the schema t, in "allOf" if t has a pattern already.
+func addPattern(t *Type, pattern string) {
+	if t.Pattern == "" {
+		t.Pattern = pattern
+		return
+	}
+	t.AllOf = append(t.AllOf, &Type{Pattern: pattern})
+}
+
+// greater reports whether the number x is greater than y.
+func greater(x, y json.Number) bool {
+	a, okA := new(big.Rat).SetString(string(x))
+	b, okB := new(big.Rat).SetString(string(y))
+
+	return okA && okB && a.Cmp(b) > 0
+}
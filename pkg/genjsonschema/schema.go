@@ -0,0 +1,587 @@
+// Copyright 2019 The protoc-gen-jsonschema Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genjsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Draft is a version of the JSON Schema specification.
+type Draft int
+
+// list of the supported drafts.
+const (
+	Draft04 Draft = iota
+	Draft06
+	Draft07
+	Draft201909
+	Draft202012
+)
+
+// drafts is the supported drafts keyed by the value of the draft parameter.
+var drafts = map[string]Draft{
+	"04":      Draft04,
+	"06":      Draft06,
+	"07":      Draft07,
+	"2019-09": Draft201909,
+	"2020-12": Draft202012,
+}
+
+// uri returns the meta-schema URI of the draft, which is the value of "$schema".
+func (d Draft) uri() string {
+	switch d {
+	case Draft06:
+		return "http://json-schema.org/draft-06/schema#"
+	case Draft07:
+		return "http://json-schema.org/draft-07/schema#"
+	case Draft201909:
+		return "https://json-schema.org/draft/2019-09/schema"
+	case Draft202012:
+		return "https://json-schema.org/draft/2020-12/schema"
+	default:
+		return "http://json-schema.org/draft-04/schema#"
+	}
+}
+
+// definitionsKeyword returns the keyword which holds the definitions in the draft.
+func (d Draft) definitionsKeyword() string {
+	if d >= Draft201909 {
+		return "$defs"
+	}
+	return "definitions"
+}
+
+// Type represents a JSON Schema.
+//
+// The Type models the keywords independently of the drafts, in terms of the latest one. For example, the exclusive
+// bounds are numbers as draft-06 defines, and the definitions are referred by their names. The emitter writes them in
+// the keywords of the selected draft.
+type Type struct {
+	Ref     string          // name of the referenced definition
+	Boolean *bool           // the boolean schema "true" or "false" if non-nil
+	Raw     json.RawMessage // the literal JSON of the schema which replaces all the other members if non-nil
+
+	// annotations
+	Title            string
+	Description      string
+	Default          interface{}
+	Examples         []interface{}
+	ReadOnly         bool
+	WriteOnly        bool
+	EnumDescriptions []string // the extension of VS Code and yaml-language-server, which describes each value of "enum"
+
+	// any instance type
+	Type   string
+	Format string
+	Enum   []interface{}
+	Const  interface{}
+
+	// numbers
+	MultipleOf       json.Number
+	Minimum          json.Number
+	ExclusiveMinimum json.Number
+	Maximum          json.Number
+	ExclusiveMaximum json.Number
+
+	// strings
+	MinLength *uint64
+	MaxLength *uint64
+	Pattern   string
+
+	// arrays
+	PrefixItems []*Type
+	Items       *Type
+	MinItems    *uint64
+	MaxItems    *uint64
+	UniqueItems bool
+
+	// objects
+	MinProperties         *uint64
+	MaxProperties         *uint64
+	Required              []string
+	Properties            *Properties
+	PatternProperties     map[string]*Type
+	AdditionalProperties  *Type
+	PropertyNames         *Type
+	DependentRequired     map[string][]string
+	DependentSchemas      map[string]*Type
+	UnevaluatedProperties *Type
+
+	// applicators
+	AllOf []*Type
+	AnyOf []*Type
+	OneOf []*Type
+	Not   *Type
+
+	Definitions Definitions
+
+	// the members whose keys start with "x-", which JSON Schema and OpenAPI leave to the applications, e.g. "x-cel"
+	Extensions map[string]interface{}
+}
+
+// Definitions hold schema definitions keyed by their names.
+type Definitions map[string]*Type
+
+// Properties hold the schemas of the properties in the order they are added, which is the declaration order of the
+// fields for the messages.
+type Properties struct {
+	names   []string
+	schemas map[string]*Type
+}
+
+// Set sets the schema of the property named name. The property keeps its position if it is already set.
+func (p *Properties) Set(name string, t *Type) {
+	if p.schemas == nil {
+		p.schemas = make(map[string]*Type)
+	}
+	if _, ok := p.schemas[name]; !ok {
+		p.names = append(p.names, name)
+	}
+	p.schemas[name] = t
+}
+
+// Get returns the schema of the property named name.
+func (p *Properties) Get(name string) (*Type, bool) {
+	if p == nil {
+		return nil, false
+	}
+	t, ok := p.schemas[name]
+	return t, ok
+}
+
+// Len returns the number of the properties.
+func (p *Properties) Len() int {
+	if p == nil {
+		return 0
+	}
+	return len(p.names)
+}
+
+// Names returns the names of the properties in order.
+func (p *Properties) Names() []string {
+	if p == nil {
+		return nil
+	}
+	return p.names
+}
+
+// boolSchema returns the boolean schema, which accepts any instance if b is true, and no instance otherwise.
+func boolSchema(b bool) *Type {
+	return &Type{Boolean: &b}
+}
+
+// object is a JSON object which keeps the order of its members.
+type object struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// set sets the member of the object, ignoring the absent values such as nil and empty strings.
+//
+// The booleans are always set, because false is the boolean schema which accepts no instance.
+func (o *object) set(key string, value interface{}) {
+	switch v := value.(type) {
+	case nil:
+		return
+	case string:
+		if v == "" {
+			return
+		}
+	case json.Number:
+		if v == "" {
+			return
+		}
+	case *uint64:
+		if v == nil {
+			return
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			return
+		}
+	case []string:
+		if len(v) == 0 {
+			return
+		}
+	case *object:
+		if v == nil {
+			return
+		}
+	}
+
+	if o.values == nil {
+		o.values = make(map[string]interface{})
+	}
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// get returns the member of the object, or nil if it is absent.
+func (o *object) get(key string) interface{} {
+	return o.values[key]
+}
+
+// delete removes the member of the object.
+func (o *object) delete(key string) {
+	if _, ok := o.values[key]; !ok {
+		return
+	}
+	delete(o.values, key)
+	for i, k := range o.keys {
+		if k == key {
+			o.keys = append(o.keys[:i], o.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// MarshalJSON implements json.Marshaler.
+func (o *object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// emitter writes the schemas in the keywords of a draft.
+type emitter struct {
+	draft          Draft
+	openapi        string // version of the OpenAPI document which embeds the schemas, or empty for JSON Schema documents
+	sortProperties bool   // write the properties in alphabetical order rather than the declaration order
+}
+
+// newEmitter returns the emitter for the output format of opts.
+func newEmitter(opts *options) *emitter {
+	e := &emitter{
+		draft:          opts.Draft,
+		sortProperties: opts.PropertyOrder == PropertyOrderAlphabetical,
+	}
+	switch opts.outputFormat {
+	case outputFormatOpenAPI3:
+		e.openapi = openAPIVersion30
+	case outputFormatOpenAPI31:
+		e.openapi = openAPIVersion31
+	}
+
+	return e
+}
+
+// openAPI30 reports whether the schemas are embedded in an OpenAPI 3.0 document, whose Schema Object is the
+// extended subset of draft-04 rather than a JSON Schema dialect.
+func (e *emitter) openAPI30() bool {
+	return e.openapi == openAPIVersion30
+}
+
+// ref returns the JSON Reference to the definition named name.
+func (e *emitter) ref(name string) string {
+	if e.openapi != "" {
+		return "#/components/schemas/" + name
+	}
+	return "#/" + e.draft.definitionsKeyword() + "/" + name
+}
+
+// root returns the JSON representation of the root schema, which declares the draft by "$schema".
+func (e *emitter) root(t *Type) interface{} {
+	o := &object{}
+	o.set("$schema", e.draft.uri())
+	e.members(o, t)
+
+	return o
+}
+
+// schema returns the JSON representation of the schema.
+func (e *emitter) schema(t *Type) interface{} {
+	if t == nil {
+		return nil
+	}
+	if t.Raw != nil {
+		return t.Raw
+	}
+	if t.Boolean != nil {
+		if e.openAPI30() || e.draft < Draft06 {
+			// draft-04 and OpenAPI 3.0 have no boolean schemas except for "additionalProperties"
+			if *t.Boolean {
+				return &object{}
+			}
+			return e.schema(&Type{Not: &Type{}})
+		}
+		return *t.Boolean
+	}
+
+	o := &object{}
+	e.members(o, t)
+
+	return o
+}
+
+// members sets the keywords of the schema to o.
+func (e *emitter) members(o *object, t *Type) {
+	if t.Ref != "" {
+		o.set("$ref", e.ref(t.Ref))
+	}
+
+	o.set("title", t.Title)
+	o.set("description", t.Description)
+	o.set("type", t.Type)
+	o.set("format", t.Format)
+
+	enum := t.Enum
+	if t.Const != nil && e.draft < Draft06 {
+		// "const" is introduced in draft-06
+		enum = []interface{}{t.Const}
+	} else {
+		o.set("const", t.Const)
+	}
+	o.set("enum", enum)
+	if e.openapi != "" {
+		// OpenAPI allows the unknown keywords only as the extensions
+		o.set("x-enumDescriptions", t.EnumDescriptions)
+	} else {
+		o.set("enumDescriptions", t.EnumDescriptions)
+	}
+	o.set("default", t.Default)
+	if e.openAPI30() && len(t.Examples) > 0 {
+		// OpenAPI 3.0 has a single "example" instead of "examples"
+		o.set("example", t.Examples[0])
+	} else {
+		o.set("examples", t.Examples)
+	}
+	readOnly, writeOnly := "readOnly", "writeOnly"
+	if e.draft < Draft07 && !e.openAPI30() {
+		// "readOnly" and "writeOnly" are introduced in draft-07, and written as the extensions before it
+		readOnly, writeOnly = "x-readOnly", "x-writeOnly"
+	}
+	if t.ReadOnly {
+		o.set(readOnly, true)
+	}
+	if t.WriteOnly {
+		o.set(writeOnly, true)
+	}
+
+	o.set("multipleOf", t.MultipleOf)
+	if e.draft < Draft06 {
+		// the exclusive bounds are the boolean modifiers of "minimum" and "maximum" in draft-04
+		if t.ExclusiveMinimum != "" {
+			o.set("minimum", t.ExclusiveMinimum)
+			o.set("exclusiveMinimum", true)
+		} else {
+			o.set("minimum", t.Minimum)
+		}
+		if t.ExclusiveMaximum != "" {
+			o.set("maximum", t.ExclusiveMaximum)
+			o.set("exclusiveMaximum", true)
+		} else {
+			o.set("maximum", t.Maximum)
+		}
+	} else {
+		o.set("minimum", t.Minimum)
+		o.set("exclusiveMinimum", t.ExclusiveMinimum)
+		o.set("maximum", t.Maximum)
+		o.set("exclusiveMaximum", t.ExclusiveMaximum)
+	}
+
+	o.set("minLength", t.MinLength)
+	o.set("maxLength", t.MaxLength)
+	o.set("pattern", t.Pattern)
+
+	if len(t.PrefixItems) > 0 && !e.openAPI30() {
+		if e.draft >= Draft202012 {
+			o.set("prefixItems", e.schemas(t.PrefixItems))
+			o.set("items", e.schema(t.Items))
+		} else {
+			// the tuple validation is the array form of "items" until 2020-12
+			o.set("items", e.schemas(t.PrefixItems))
+			o.set("additionalItems", e.schema(t.Items))
+		}
+	} else {
+		o.set("items", e.schema(t.Items))
+	}
+	o.set("minItems", t.MinItems)
+	o.set("maxItems", t.MaxItems)
+	if t.UniqueItems {
+		o.set("uniqueItems", true)
+	}
+
+	o.set("minProperties", t.MinProperties)
+	o.set("maxProperties", t.MaxProperties)
+	if len(t.Required) > 0 {
+		o.set("required", t.Required)
+	}
+	o.set("properties", e.properties(t.Properties))
+	additionalProperties, patternProperties := t.AdditionalProperties, t.PatternProperties
+	if e.draft < Draft06 && !e.openAPI30() && t.PropertyNames != nil && additionalProperties != nil && len(patternProperties) == 0 {
+		// "propertyNames" is introduced in draft-06, so the keys matching it are the pattern of the values before
+		// it, and the keys it cannot be written as a pattern of are not constrained
+		if pattern, ok := keyPattern(t.PropertyNames); ok {
+			patternProperties = map[string]*Type{pattern: additionalProperties}
+			additionalProperties = boolSchema(false)
+		}
+	}
+	if additionalProperties != nil && additionalProperties.Boolean != nil {
+		o.set("additionalProperties", *additionalProperties.Boolean)
+	} else {
+		o.set("additionalProperties", e.schema(additionalProperties))
+	}
+	if !e.openAPI30() {
+		// OpenAPI 3.0 lacks the keywords below, so the constraints are loosened rather than making the document invalid
+		o.set("patternProperties", e.schemaMap(patternProperties))
+		if e.draft >= Draft06 {
+			o.set("propertyNames", e.schema(t.PropertyNames))
+		}
+	}
+
+	switch {
+	case e.openAPI30():
+		// "dependencies" and "unevaluatedProperties" are neither in OpenAPI 3.0
+	case e.draft >= Draft201909:
+		o.set("dependentRequired", e.dependentRequired(t.DependentRequired))
+		o.set("dependentSchemas", e.schemaMap(t.DependentSchemas))
+		o.set("unevaluatedProperties", e.schema(t.UnevaluatedProperties))
+	case len(t.DependentRequired) > 0 || len(t.DependentSchemas) > 0:
+		// "dependencies" is split into "dependentRequired" and "dependentSchemas" in 2019-09
+		dependencies := e.schemaMap(t.DependentSchemas)
+		if dependencies == nil {
+			dependencies = &object{}
+		}
+		for _, key := range sortedKeys(t.DependentRequired) {
+			dependencies.set(key, t.DependentRequired[key])
+		}
+		o.set("dependencies", dependencies)
+	}
+
+	o.set("allOf", e.schemas(t.AllOf))
+	o.set("anyOf", e.schemas(t.AnyOf))
+	o.set("oneOf", e.schemas(t.OneOf))
+	o.set("not", e.schema(t.Not))
+
+	for _, key := range sortedKeys(t.Extensions) {
+		o.set(key, t.Extensions[key])
+	}
+
+	if e.openapi == "" {
+		o.set(e.draft.definitionsKeyword(), e.schemaMap(t.Definitions))
+	} else {
+		e.rewriteNull(o, t)
+	}
+}
+
+// schemas returns the JSON representations of the list of schemas.
+func (e *emitter) schemas(ts []*Type) interface{} {
+	if len(ts) == 0 {
+		return nil
+	}
+
+	a := make([]interface{}, len(ts))
+	for i, t := range ts {
+		a[i] = e.schema(t)
+	}
+
+	return a
+}
+
+// schemaMap returns the JSON representations of the schemas keyed by their names, in the order of the names.
+func (e *emitter) schemaMap(m map[string]*Type) *object {
+	if len(m) == 0 {
+		return nil
+	}
+
+	o := &object{}
+	for _, key := range sortedKeys(m) {
+		o.set(key, e.schema(m[key]))
+	}
+
+	return o
+}
+
+// properties returns the JSON representations of the schemas of the properties.
+func (e *emitter) properties(p *Properties) *object {
+	if p.Len() == 0 {
+		return nil
+	}
+
+	names := p.Names()
+	if e.sortProperties {
+		names = append([]string(nil), names...)
+		sort.Strings(names)
+	}
+
+	o := &object{}
+	for _, name := range names {
+		t, _ := p.Get(name)
+		o.set(name, e.schema(t))
+	}
+
+	return o
+}
+
+// dependentRequired returns the JSON representation of "dependentRequired".
+func (e *emitter) dependentRequired(m map[string][]string) *object {
+	if len(m) == 0 {
+		return nil
+	}
+
+	o := &object{}
+	for _, key := range sortedKeys(m) {
+		o.set(key, m[key])
+	}
+
+	return o
+}
+
+// keyPattern returns the pattern which matches the same keys as the schema of the keys t, if t only has a pattern or
+// an enum of strings.
+func keyPattern(t *Type) (string, bool) {
+	switch {
+	case reflect.DeepEqual(*t, Type{Pattern: t.Pattern}) && t.Pattern != "":
+		return t.Pattern, true
+	case reflect.DeepEqual(*t, Type{Enum: t.Enum}) && len(t.Enum) > 0:
+		keys := make([]string, len(t.Enum))
+		for i, v := range t.Enum {
+			key, ok := v.(string)
+			if !ok {
+				return "", false
+			}
+			keys[i] = regexp.QuoteMeta(key)
+		}
+		return "^(" + strings.Join(keys, "|") + ")$", true
+	}
+
+	return "", false
+}
+
+// sortedKeys returns the sorted keys of the map.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,107 @@
+// Copyright 2019 The protoc-gen-jsonschema Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genjsonschema
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEmitter(t *testing.T) {
+	minItems := uint64(1)
+	tests := []struct {
+		name   string
+		schema *Type
+		want   map[Draft]string
+	}{
+		{
+			name:   "exclusive bounds",
+			schema: &Type{Type: "integer", ExclusiveMinimum: "0", Maximum: "10"},
+			want: map[Draft]string{
+				Draft04:     `{"type":"integer","minimum":0,"exclusiveMinimum":true,"maximum":10}`,
+				Draft07:     `{"type":"integer","exclusiveMinimum":0,"maximum":10}`,
+				Draft202012: `{"type":"integer","exclusiveMinimum":0,"maximum":10}`,
+			},
+		},
+		{
+			name:   "const",
+			schema: &Type{Const: false},
+			want: map[Draft]string{
+				Draft04: `{"enum":[false]}`,
+				Draft06: `{"const":false}`,
+			},
+		},
+		{
+			name:   "tuple",
+			schema: &Type{Type: "array", PrefixItems: []*Type{{Type: "string"}}, Items: boolSchema(false), MinItems: &minItems},
+			want: map[Draft]string{
+				Draft07:     `{"type":"array","items":[{"type":"string"}],"additionalItems":false,"minItems":1}`,
+				Draft201909: `{"type":"array","items":[{"type":"string"}],"additionalItems":false,"minItems":1}`,
+				Draft202012: `{"type":"array","prefixItems":[{"type":"string"}],"items":false,"minItems":1}`,
+			},
+		},
+		{
+			name: "dependencies",
+			schema: &Type{
+				DependentRequired: map[string][]string{"b": {"a"}},
+				DependentSchemas:  map[string]*Type{"c": {Not: &Type{Required: []string{"a"}}}},
+			},
+			want: map[Draft]string{
+				Draft07:     `{"dependencies":{"c":{"not":{"required":["a"]}},"b":["a"]}}`,
+				Draft201909: `{"dependentRequired":{"b":["a"]},"dependentSchemas":{"c":{"not":{"required":["a"]}}}}`,
+			},
+		},
+		{
+			name:   "read only",
+			schema: &Type{ReadOnly: true, WriteOnly: true},
+			want: map[Draft]string{
+				Draft04: `{"x-readOnly":true,"x-writeOnly":true}`,
+				Draft06: `{"x-readOnly":true,"x-writeOnly":true}`,
+				Draft07: `{"readOnly":true,"writeOnly":true}`,
+			},
+		},
+		{
+			name:   "property names",
+			schema: &Type{AdditionalProperties: &Type{Type: "integer"}, PropertyNames: &Type{Enum: []interface{}{"a.b", "c"}}},
+			want: map[Draft]string{
+				Draft04: `{"additionalProperties":false,"patternProperties":{"^(a\\.b|c)$":{"type":"integer"}}}`,
+				Draft06: `{"additionalProperties":{"type":"integer"},"propertyNames":{"enum":["a.b","c"]}}`,
+			},
+		},
+		{
+			name:   "property names without a pattern",
+			schema: &Type{AdditionalProperties: &Type{Type: "integer"}, PropertyNames: &Type{MinLength: &minItems}},
+			want: map[Draft]string{
+				Draft04: `{"additionalProperties":{"type":"integer"}}`,
+				Draft06: `{"additionalProperties":{"type":"integer"},"propertyNames":{"minLength":1}}`,
+			},
+		},
+		{
+			name:   "definitions",
+			schema: &Type{Ref: "a.B", Definitions: Definitions{"a.B": boolSchema(true)}},
+			want: map[Draft]string{
+				// draft-04 has no boolean schemas
+				Draft04:     `{"$ref":"#/definitions/a.B","definitions":{"a.B":{}}}`,
+				Draft07:     `{"$ref":"#/definitions/a.B","definitions":{"a.B":true}}`,
+				Draft201909: `{"$ref":"#/$defs/a.B","$defs":{"a.B":true}}`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			for d, want := range tt.want {
+				e := &emitter{draft: d}
+				got, err := json.Marshal(e.schema(tt.schema))
+				if err != nil {
+					t.Fatalf("json.Marshal: %v", err)
+				}
+				if string(got) != want {
+					t.Errorf("%s:\n got: %s\nwant: %s", d.uri(), got, want)
+				}
+			}
+		})
+	}
+}
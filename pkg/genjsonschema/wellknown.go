@@ -0,0 +1,108 @@
+// Copyright 2019 The protoc-gen-jsonschema Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package genjsonschema
+
+import (
+	"github.com/xeipuuv/gojsonschema"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// wellKnownTypes is the schemas of the well-known types in their canonical protojson representations.
+//
+// Each function returns a new schema, because the schema of a field is described by its own comments.
+var wellKnownTypes = map[protoreflect.FullName]func() *Type{
+	"google.protobuf.Timestamp": func() *Type {
+		// RFC 3339, e.g. "1972-01-01T10:00:20.021Z"
+		return &Type{Type: gojsonschema.TYPE_STRING, Format: "date-time"}
+	},
+	"google.protobuf.Duration": func() *Type {
+		// seconds with up to 9 fractional digits followed by "s", e.g. "1.5s"
+		return &Type{Type: gojsonschema.TYPE_STRING, Pattern: `^-?[0-9]+(\.[0-9]{1,9})?s$`}
+	},
+	"google.protobuf.FieldMask": func() *Type {
+		// comma-separated lowerCamelCase paths, e.g. "user.displayName,photo"
+		return &Type{Type: gojsonschema.TYPE_STRING, Pattern: `^([a-zA-Z][a-zA-Z0-9]*(\.[a-zA-Z][a-zA-Z0-9]*)*(,[a-zA-Z][a-zA-Z0-9]*(\.[a-zA-Z][a-zA-Z0-9]*)*)*)?$`}
+	},
+	"google.protobuf.Struct": func() *Type {
+		return &Type{Type: gojsonschema.TYPE_OBJECT, AdditionalProperties: boolSchema(true)}
+	},
+	"google.protobuf.Value": func() *Type {
+		// any JSON value
+		return &Type{}
+	},
+	"google.protobuf.ListValue": func() *Type {
+		return &Type{Type: gojsonschema.TYPE_ARRAY}
+	},
+	"google.protobuf.Any": func() *Type {
+		jsonSchemaType := &Type{
+			Type:                 gojsonschema.TYPE_OBJECT,
+			Properties:           &Properties{},
+			Required:             []string{"@type"},
+			AdditionalProperties: boolSchema(true),
+		}
+		jsonSchemaType.Properties.Set("@type", &Type{Type: gojsonschema.TYPE_STRING})
+
+		return jsonSchemaType
+	},
+	"google.protobuf.Empty": func() *Type {
+		return &Type{Type: gojsonschema.TYPE_OBJECT, AdditionalProperties: boolSchema(false)}
+	},
+}
+
+// wrapperTypes is the set of the wrapper well-known types, which are represented as their "value" field or null.
+var wrapperTypes = map[protoreflect.FullName]bool{
+	"google.protobuf.DoubleValue": true,
+	"google.protobuf.FloatValue":  true,
+	"google.protobuf.Int64Value":  true,
+	"google.protobuf.UInt64Value": true,
+	"google.protobuf.Int32Value":  true,
+	"google.protobuf.UInt32Value": true,
+	"google.protobuf.BoolValue":   true,
+	"google.protobuf.StringValue": true,
+	"google.protobuf.BytesValue":  true,
+}
+
+// convertWellKnownType converts the well-known type into the schema of its protojson representation.
+//
+// It returns nil if the message is not a well-known type.
+func (c *converter) convertWellKnownType(msg protoreflect.MessageDescriptor) (*Type, error) {
+	if wellKnownType, ok := wellKnownTypes[msg.FullName()]; ok {
+		return wellKnownType(), nil
+	}
+
+	if wrapperTypes[msg.FullName()] {
+		jsonSchemaType, err := c.convertField(msg.Fields().Get(0))
+		if err != nil {
+			return nil, err
+		}
+
+		return nullable(jsonSchemaType), nil
+	}
+
+	return nil, nil
+}
+
+// nullable returns the schema which accepts null in addition to the values jsonSchemaType accepts.
+func nullable(jsonSchemaType *Type) *Type {
+	if jsonSchemaType.Type == gojsonschema.TYPE_NULL {
+		return jsonSchemaType
+	}
+	for _, t := range jsonSchemaType.OneOf {
+		if t.Type == gojsonschema.TYPE_NULL {
+			return jsonSchemaType
+		}
+	}
+	if o, ok := (&emitter{}).schema(jsonSchemaType).(*object); ok && len(o.keys) == 0 {
+		// the empty schema, e.g. of google.protobuf.Value, accepts null already
+		return jsonSchemaType
+	}
+
+	return &Type{
+		OneOf: []*Type{
+			{Type: gojsonschema.TYPE_NULL},
+			jsonSchemaType,
+		},
+	}
+}